@@ -3,17 +3,97 @@ package main
 import (
     "github.com/golang/glog"
     "github.com/scampi/sparql-log/extract"
+    "github.com/scampi/sparql-log/qparser"
     "os"
     "flag"
     "fmt"
+    "sort"
+    "strings"
+    "time"
 )
 
-var logFormat extract.LogFormat
-var input = flag.String("input", "", "The path to the log folder")
+var logFormat = extract.TOMCAT
+var input = flag.String("input", "", "The path to the log folder, or - to read the log from the standard input")
 var output = flag.String("output", "", "The path to the output folder")
+var include patterns
+var exclude = patterns{ list : []string{ "*.md5", "*.sha1", "*.sha256", "*.asc", ".*" } }
+var order = extract.ByName
+var onError = extract.Abort
+var outputFormat = extract.TEXT
+var quarantine = flag.String("quarantine", "", "The path to the file receiving the queries which cannot be decoded or parsed, quarantine.gz in the output folder by default")
+var dedup = flag.String("dedup", "", "The path to an index of the components written by previous runs, which are not written again")
+var dedupReset = flag.Bool("dedup-reset", false, "Empty the index given with -dedup before the extraction")
+var dedupInfo = flag.Bool("dedup-info", false, "Print the size of the index given with -dedup, and exit")
+var sqlite = flag.String("sqlite", "", "The path to a SQLite database receiving the log entries, queries and components, appended to if it exists")
+var lsq = flag.String("lsq", "", "The path to an RDF file describing the queries with the LSQ vocabulary, in N-Triples (.nt) or Turtle (.ttl), optionally followed by .gz")
+var lsqBase = flag.String("lsq-base", extract.DefaultLSQBase, "The namespace of the resources of the LSQ file")
+var partition = flag.String("partition", extract.DefaultPartition, "The template of the output files' keys, with the placeholders {complexity}, {patterns}, {file}, {day}, {hour}, {host} and {form}, e.g., {day}/{complexity}")
+var maxOpenFiles = flag.Int("max-open-files", extract.DefaultMaxOpenFiles, "The maximum number of output files open at once")
+var prefixes = namespaces{}
+var workers = flag.Int("workers", 0, "The number of log files read and of queries parsed concurrently, the number of CPUs if 0")
+
+// patterns is a list of comma separated glob patterns
+type patterns struct {
+    list []string
+    // set is true once the flag is given, which replaces the default patterns
+    set bool
+}
+
+func (p *patterns) String() string {
+    return strings.Join(p.list, ",")
+}
+
+// Set method needed for the flag package
+func (p *patterns) Set(s string) error {
+    if !p.set {
+        p.list, p.set = nil, true
+    }
+    p.list = append(p.list, strings.Split(s, ",")...)
+    return nil
+}
+
+// namespaces is a list of comma separated prefix=namespace declarations,
+// or dbpedia for the prefixes of the DBpedia endpoint
+type namespaces map[string]string
+
+func (ns namespaces) String() string {
+    var decls []string
+    for prefix, namespace := range ns {
+        decls = append(decls, prefix + "=" + namespace)
+    }
+    sort.Strings(decls)
+    return strings.Join(decls, ",")
+}
+
+// Set method needed for the flag package
+func (ns namespaces) Set(s string) error {
+    for _, decl := range strings.Split(s, ",") {
+        if decl == "dbpedia" {
+            for prefix, namespace := range qparser.DBpediaPrefixes {
+                ns[prefix] = namespace
+            }
+            continue
+        }
+        kv := strings.SplitN(decl, "=", 2)
+        if len(kv) != 2 {
+            return fmt.Errorf("Bad prefix declaration [%v], expected prefix=namespace", decl)
+        }
+        ns[kv[0]] = kv[1]
+    }
+    return nil
+}
 
 func init() {
-    flag.Var(&logFormat, "log-format", "The format of the logs")
+    flag.Var(&logFormat, "log-format", "The format of the logs, one of: " + strings.Join(extract.Formats(), ", ") +
+        ". Options of the format follow a colon, e.g., json:query=request.query,time=ts,client=remote,status=code")
+    flag.Var(&include, "include", "Comma separated glob patterns of the log files to process, matched against the path relative to the input folder and the file name")
+    flag.Var(&exclude, "exclude", "Comma separated glob patterns of the log files and folders to skip")
+    flag.Var(&order, "order", "The order in which the log files are processed, by name or mtime")
+    flag.Var(&outputFormat, "output-format", "The format of the output files: text, or jsonl, csv and tsv for a record per component with its query, count and log metadata")
+    flag.Var(&onError, "on-error", "What to do with a log that cannot be read or an entry that cannot be decoded: " +
+        "abort, skip it, or collect the errors to print them at the end")
+    flag.Var(prefixes, "prefixes", "Comma separated prefix=namespace declarations of the prefixes the queries use without declaring them, " +
+        "or dbpedia for the prefixes the DBpedia endpoint declares")
 }
 
 func missingOption(option string) {
@@ -26,8 +106,69 @@ func main() {
     flag.Parse()
     defer glog.Flush()
 
+    if *dedupInfo {
+        if *dedup == "" { missingOption("dedup") }
+        printDedupInfo(*dedup)
+        return
+    }
     if *input == "" { missingOption("input") }
     if *output == "" { missingOption("output") }
-    extract.Extract(logFormat, *input, *output)
+    opts := &extract.Options{
+        Include : include.list,
+        Exclude : exclude.list,
+        Order : order,
+        Workers : *workers,
+        OnError : onError,
+        Quarantine : *quarantine,
+        Dedup : *dedup,
+        OutputFormat : outputFormat,
+        SQLite : *sqlite,
+        LSQ : *lsq,
+        LSQBase : *lsqBase,
+        Partition : *partition,
+        MaxOpenFiles : *maxOpenFiles,
+        Prefixes : prefixes,
+    }
+    if *dedupReset && *dedup != "" {
+        if err := extract.ResetDedupIndex(*dedup); err != nil {
+            glog.Exit(err)
+        }
+    }
+    summary, err := extract.Extract(logFormat, *input, *output, opts)
+    if summary != nil {
+        printSummary(summary)
+    }
+    if err != nil {
+        glog.Exit(err)
+    }
 }
 
+// printSummary prints what was extracted and skipped to the standard error
+func printSummary(s *extract.Summary) {
+    fmt.Fprintf(os.Stderr, "%v logs, %v queries (%v unparsed), %v components, %v quarantined\n", s.Logs, s.Queries, s.Unparsed, s.Components, s.Quarantined)
+    if s.SkippedLogs != 0 || s.SkippedEntries != 0 {
+        fmt.Fprintf(os.Stderr, "Skipped %v logs and %v entries\n", s.SkippedLogs, s.SkippedEntries)
+    }
+    for _, err := range s.Errors {
+        fmt.Fprintln(os.Stderr, err)
+    }
+}
+
+
+// printDedupInfo prints the number of components in the dedup index
+func printDedupInfo(path string) {
+    fi, err := os.Stat(path)
+    if os.IsNotExist(err) {
+        fmt.Printf("%v: no index\n", path)
+        return
+    }
+    if err != nil {
+        glog.Exit(err)
+    }
+    d, err := extract.OpenDedupIndex(path)
+    if err != nil {
+        glog.Exit(err)
+    }
+    defer d.Close()
+    fmt.Printf("%v: %v components, %v bytes, updated %v\n", path, d.Len(), fi.Size(), fi.ModTime().Format(time.RFC3339))
+}
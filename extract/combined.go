@@ -0,0 +1,158 @@
+package extract
+
+import (
+    "bufio"
+    "bytes"
+    "fmt"
+    "strconv"
+    "time"
+)
+
+func init() {
+    RegisterFormat(string(COMBINED), &Format{
+        Split: combined,
+        Entry: combinedEntry,
+    })
+}
+
+// The layout of the timestamp in the Common and Combined Log Formats
+const clfTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// clfEntry is a line in the Common or Combined Log Format
+//   %h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-agent}i"
+// The referer and user agent fields are optional.
+type clfEntry struct {
+    Metadata
+    // RawQuery is the encoded query string of the request URI
+    RawQuery string
+}
+
+// Combined reads the log file line by line and returns the lines with a SPARQL query.
+func combined(data []byte, atEOF bool) (advance int, token []byte, err error) {
+    skipped := 0
+    for {
+        advance, token, err = bufio.ScanLines(data[skipped:], atEOF)
+        if err != nil || advance == 0 {
+            // consume the lines without a query and wait for more data
+            return skipped, nil, err
+        }
+        if bytes.Contains(token, []byte("query=")) {
+            return skipped + advance, token, nil
+        }
+        skipped += advance
+    }
+}
+
+// CombinedEntry returns the query parameter of the line's request URI, with
+// the fields of the log line.
+func combinedEntry(line []byte) (*Entry, error) {
+    e, err := parseCLF(line)
+    if err != nil {
+        return nil, err
+    }
+    r := &sparqlRequest{ Method : e.Method, RawQuery : e.RawQuery }
+    return r.entry(e.Metadata)
+}
+
+// parseCLF parses a line in the Common or Combined Log Format
+func parseCLF(line []byte) (*clfEntry, error) {
+    e := &clfEntry{}
+    fields := make([]string, 0, 9)
+    rest := bytes.TrimSpace(line)
+    for len(rest) != 0 {
+        var field string
+        var err error
+        field, rest, err = nextCLFField(rest)
+        if err != nil {
+            return nil, fmt.Errorf("%v in [%s]", err, line)
+        }
+        fields = append(fields, field)
+    }
+    if len(fields) != 7 && len(fields) != 9 {
+        return nil, fmt.Errorf("Expected 7 or 9 fields, got %v in [%s]", len(fields), line)
+    }
+
+    e.Client, e.Ident, e.User = clfValue(fields[0]), clfValue(fields[1]), clfValue(fields[2])
+    t, err := time.Parse(clfTimeLayout, fields[3])
+    if err != nil {
+        return nil, err
+    }
+    e.Time = t
+    if err := e.parseRequest(fields[4]); err != nil {
+        return nil, err
+    }
+    if e.Status, err = strconv.Atoi(fields[5]); err != nil {
+        return nil, fmt.Errorf("Bad status [%v] in [%s]", fields[5], line)
+    }
+    if b := clfValue(fields[6]); b != "" {
+        if e.Bytes, err = strconv.ParseInt(b, 10, 64); err != nil {
+            return nil, fmt.Errorf("Bad size [%v] in [%s]", b, line)
+        }
+    }
+    if len(fields) == 9 {
+        e.Referer, e.UserAgent = clfValue(fields[7]), clfValue(fields[8])
+    }
+    return e, nil
+}
+
+// parseRequest parses the request line, e.g., "GET /sparql?query=... HTTP/1.1"
+func (e *clfEntry) parseRequest(request string) error {
+    parts := bytes.Fields([]byte(request))
+    switch len(parts) {
+    case 3:
+        e.Protocol = string(parts[2])
+        fallthrough
+    case 2:
+        e.Method = string(parts[0])
+        e.Path = string(parts[1])
+    default:
+        return fmt.Errorf("Bad request line [%v]", request)
+    }
+    if ind := bytes.IndexByte(parts[1], '?'); ind != -1 {
+        e.Path = string(parts[1][:ind])
+        e.RawQuery = string(parts[1][ind+1:])
+    }
+    return nil
+}
+
+// nextCLFField returns the next space separated field of the line, and the rest.
+// A field is either a bracketed timestamp, a double-quoted string with
+// backslash escapes, or a sequence of non-space characters.
+func nextCLFField(line []byte) (field string, rest []byte, err error) {
+    var end int
+    switch line[0] {
+    case '[':
+        end = bytes.IndexByte(line, ']')
+        if end == -1 {
+            return "", nil, fmt.Errorf("Unterminated timestamp")
+        }
+        field, end = string(line[1:end]), end+1
+    case '"':
+        var buf []byte
+        for end = 1; end < len(line) && line[end] != '"'; end++ {
+            if line[end] == '\\' && end+1 < len(line) {
+                end++
+            }
+            buf = append(buf, line[end])
+        }
+        if end == len(line) {
+            return "", nil, fmt.Errorf("Unterminated quoted string")
+        }
+        field, end = string(buf), end+1
+    default:
+        end = bytes.IndexByte(line, ' ')
+        if end == -1 {
+            end = len(line)
+        }
+        field = string(line[:end])
+    }
+    return field, bytes.TrimLeft(line[end:], " "), nil
+}
+
+// clfValue returns the field's value, with "-" denoting an absent value
+func clfValue(field string) string {
+    if field == "-" {
+        return ""
+    }
+    return field
+}
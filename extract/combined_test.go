@@ -0,0 +1,65 @@
+package extract
+
+import (
+    "testing"
+    "time"
+    "reflect"
+)
+
+func TestCombinedLine(t *testing.T) {
+    line := `10.0.0.2 - bob [10/Oct/2015:13:55:36 -0700] "GET /sparql?format=json&query=select+*+%7B+%3Fs+%3Fp+%3Fo+%7D+LIMIT+10 HTTP/1.1" 200 2326 "http://example.org/\"q\"" "curl/7.0"`
+    entry, err := combinedEntry([]byte(line))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if expected := "select * { ?s ?p ?o } LIMIT 10"; entry.Query != expected {
+        t.Errorf("Expected %q, but got %q", expected, entry.Query)
+    }
+    expected := Metadata{
+        Client : "10.0.0.2",
+        User : "bob",
+        Time : time.Date(2015, time.October, 10, 13, 55, 36, 0, time.FixedZone("", -7 * 3600)),
+        Method : "GET",
+        Path : "/sparql",
+        Protocol : "HTTP/1.1",
+        Status : 200,
+        Bytes : 2326,
+        Referer : `http://example.org/"q"`,
+        UserAgent : "curl/7.0",
+    }
+    actual := entry.Metadata
+    if !actual.Time.Equal(expected.Time) {
+        t.Errorf("Expected %v, but got %v", expected.Time, actual.Time)
+    }
+    actual.Time = expected.Time
+    if !reflect.DeepEqual(expected, actual) {
+        t.Errorf("Expected %+v, but got %+v", expected, actual)
+    }
+}
+
+func TestCommonLine(t *testing.T) {
+    line := `127.0.0.1 - - [10/Oct/2015:13:55:36 +0000] "GET /sparql?query=ASK+%7B%7D" 200 -`
+    entry, err := combinedEntry([]byte(line))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if expected := "ASK {}"; entry.Query != expected {
+        t.Errorf("Expected %q, but got %q", expected, entry.Query)
+    }
+    m := entry.Metadata
+    if m.Protocol != "" || m.Bytes != 0 || m.UserAgent != "" {
+        t.Errorf("Unexpected metadata %+v", m)
+    }
+}
+
+func TestCombinedMalformed(t *testing.T) {
+    for _, line := range []string{
+        `127.0.0.1 - - [10/Oct/2015:13:55:36 +0000 "GET /sparql?query=ASK HTTP/1.1" 200 -`,
+        `127.0.0.1 - - [10/Oct/2015:13:55:36 +0000] "GET /sparql?query=ASK HTTP/1.1 200 -`,
+        `127.0.0.1 - - [10/Oct/2015:13:55:36 +0000] "GET /sparql?query=ASK HTTP/1.1" OK -`,
+    } {
+        if _, err := combinedEntry([]byte(line)); err == nil {
+            t.Errorf("Expected an error for [%v]", line)
+        }
+    }
+}
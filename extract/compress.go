@@ -0,0 +1,67 @@
+package extract
+
+import (
+    "bufio"
+    "bytes"
+    "compress/bzip2"
+    "compress/gzip"
+    "io"
+    "io/ioutil"
+    "github.com/klauspost/compress/zstd"
+    "github.com/pierrec/lz4/v4"
+    "github.com/ulikunitz/xz"
+)
+
+// codec is a compression format recognised by its magic bytes
+type codec struct {
+    name string
+    magic []byte
+    // newReader returns a reader of the decompressed data
+    newReader func(r io.Reader) (io.ReadCloser, error)
+}
+
+var codecs = []codec{
+    { "gzip", []byte{ 0x1f, 0x8b }, func(r io.Reader) (io.ReadCloser, error) {
+        // concatenated gzip members are read as a single stream
+        return gzip.NewReader(r)
+    } },
+    { "bzip2", []byte("BZh"), func(r io.Reader) (io.ReadCloser, error) {
+        return ioutil.NopCloser(bzip2.NewReader(r)), nil
+    } },
+    { "xz", []byte{ 0xfd, '7', 'z', 'X', 'Z', 0x00 }, func(r io.Reader) (io.ReadCloser, error) {
+        xr, err := xz.NewReader(r)
+        if err != nil {
+            return nil, err
+        }
+        return ioutil.NopCloser(xr), nil
+    } },
+    { "zstd", []byte{ 0x28, 0xb5, 0x2f, 0xfd }, func(r io.Reader) (io.ReadCloser, error) {
+        zr, err := zstd.NewReader(r)
+        if err != nil {
+            return nil, err
+        }
+        return zr.IOReadCloser(), nil
+    } },
+    { "lz4", []byte{ 0x04, 0x22, 0x4d, 0x18 }, func(r io.Reader) (io.ReadCloser, error) {
+        return ioutil.NopCloser(lz4.NewReader(r)), nil
+    } },
+}
+
+// decompress returns a reader of the decompressed data, with the name of the
+// compression format detected from the magic bytes. Data which is not
+// compressed is read as is, with an empty format name.
+// Closing the reader releases the decoder, not r.
+func decompress(r io.Reader) (io.ReadCloser, string, error) {
+    br := bufio.NewReader(r)
+    for _, c := range codecs {
+        magic, err := br.Peek(len(c.magic))
+        if err != nil && err != io.EOF {
+            return nil, "", err
+        }
+        if bytes.Equal(magic, c.magic) {
+            dr, err := c.newReader(br)
+            return dr, c.name, err
+        }
+    }
+    return ioutil.NopCloser(br), "", nil
+}
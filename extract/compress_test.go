@@ -0,0 +1,111 @@
+package extract
+
+import (
+    "bytes"
+    "compress/gzip"
+    "io"
+    "io/ioutil"
+    "os"
+    "path"
+    "strings"
+    "testing"
+    "github.com/klauspost/compress/zstd"
+    "github.com/pierrec/lz4/v4"
+    "github.com/ulikunitz/xz"
+)
+
+func compress(t *testing.T, w io.WriteCloser, data string) {
+    if _, err := w.Write([]byte(data)); err != nil {
+        t.Fatal(err)
+    }
+    if err := w.Close(); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestDecompress(t *testing.T) {
+    const log = "line 1\nline 2\n"
+
+    var multi bytes.Buffer
+    compress(t, gzip.NewWriter(&multi), "line 1\n")
+    compress(t, gzip.NewWriter(&multi), "line 2\n")
+
+    var xzData bytes.Buffer
+    xw, err := xz.NewWriter(&xzData)
+    if err != nil {
+        t.Fatal(err)
+    }
+    compress(t, xw, log)
+
+    var zstdData bytes.Buffer
+    zw, err := zstd.NewWriter(&zstdData)
+    if err != nil {
+        t.Fatal(err)
+    }
+    compress(t, zw, log)
+
+    var lz4Data bytes.Buffer
+    compress(t, lz4.NewWriter(&lz4Data), log)
+
+    for _, c := range []struct {
+        codec string
+        data []byte
+    }{
+        { "gzip", multi.Bytes() },
+        { "xz", xzData.Bytes() },
+        { "zstd", zstdData.Bytes() },
+        { "lz4", lz4Data.Bytes() },
+        { "", []byte(log) },
+        { "", []byte("B") },
+    } {
+        r, codec, err := decompress(bytes.NewReader(c.data))
+        if err != nil {
+            t.Fatal(err)
+        }
+        if codec != c.codec {
+            t.Errorf("Expected %q, but got %q", c.codec, codec)
+        }
+        actual, err := ioutil.ReadAll(r)
+        if err != nil {
+            t.Fatal(err)
+        }
+        r.Close()
+        expected := log
+        if c.codec == "" {
+            expected = string(c.data)
+        }
+        if string(actual) != expected {
+            t.Errorf("Expected %q, but got %q", expected, actual)
+        }
+    }
+}
+
+func TestDecompressNoExtension(t *testing.T) {
+    input, err := ioutil.TempDir("", "input")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(input)
+    output, err := ioutil.TempDir("", "extract")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(output)
+
+    // the codec is found from the content of the log, whose name has no extension
+    var data bytes.Buffer
+    compress(t, lz4.NewWriter(&data), tomcatLog)
+    if err := ioutil.WriteFile(path.Join(input, "access"), data.Bytes(), 0644); err != nil {
+        t.Fatal(err)
+    }
+    summary, err := Extract(TOMCAT, input, output, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if summary.Logs != 1 || summary.Queries != 3 {
+        t.Errorf("Unexpected summary %+v", *summary)
+    }
+    if content := readOutput(t, output)["query_2.gz"]; !strings.Contains(content, "<C>") {
+        t.Errorf("Expected the star of the log, got %q", content)
+    }
+}
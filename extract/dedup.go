@@ -0,0 +1,212 @@
+package extract
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/binary"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+)
+
+// The magic bytes starting a dedup index file
+const dedupMagic = "SLDEDUP1"
+
+// The size of the header of a dedup index file: the magic bytes, the size of
+// an identifier and the number of identifiers
+const dedupHeaderSize = len(dedupMagic) + 4 + 8
+
+// The number of identifiers in a block of a dedup index file
+const dedupBlockSize = 512
+
+// DedupIndex is an on-disk set of the identifiers of the connected components
+// written by previous extractions, so that incremental runs only write new
+// components. The file holds the identifiers sorted, and is searched by blocks
+// through the first identifier of each block kept in memory. The identifiers
+// added while the index is open are merged into the file when it is closed.
+type DedupIndex struct {
+    path string
+    file *os.File
+    keySize int
+    // the number of identifiers in the file
+    count int64
+    // the first identifier of each block of the file, concatenated
+    fences []byte
+    // the identifiers added since the index was opened
+    added map[string]bool
+}
+
+// OpenDedupIndex opens the dedup index file at path, which is created when
+// the index is closed if it does not exist.
+func OpenDedupIndex(path string) (*DedupIndex, error) {
+    return openDedupIndex(path, componentIDSize)
+}
+
+// openDedupIndex opens the dedup index of identifiers of keySize bytes
+func openDedupIndex(path string, keySize int) (*DedupIndex, error) {
+    d := &DedupIndex{ path : path, keySize : keySize, added : make(map[string]bool) }
+    fi, err := os.Open(path)
+    if os.IsNotExist(err) {
+        return d, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    d.file = fi
+    if err := d.readFences(); err != nil {
+        fi.Close()
+        return nil, fmt.Errorf("%v: %v", path, err)
+    }
+    return d, nil
+}
+
+// readFences checks the header of the file and reads the first identifier of each block
+func (d *DedupIndex) readFences() error {
+    r := bufio.NewReader(d.file)
+    header := make([]byte, dedupHeaderSize)
+    if _, err := io.ReadFull(r, header); err != nil {
+        return fmt.Errorf("Bad dedup index header: %v", err)
+    }
+    if string(header[:len(dedupMagic)]) != dedupMagic {
+        return fmt.Errorf("Not a dedup index")
+    }
+    if size := int(binary.BigEndian.Uint32(header[len(dedupMagic):])); size != d.keySize {
+        return fmt.Errorf("The dedup index has identifiers of %v bytes instead of %v, it must be reset", size, d.keySize)
+    }
+    d.count = int64(binary.BigEndian.Uint64(header[len(dedupMagic) + 4:]))
+    key := make([]byte, d.keySize)
+    for i := int64(0); i < d.count; i++ {
+        if _, err := io.ReadFull(r, key); err != nil {
+            return fmt.Errorf("Truncated dedup index: %v", err)
+        }
+        if i % dedupBlockSize == 0 {
+            d.fences = append(d.fences, key...)
+        }
+    }
+    return nil
+}
+
+// Len returns the number of identifiers in the index
+func (d *DedupIndex) Len() int64 {
+    return d.count + int64(len(d.added))
+}
+
+// Path returns the path to the index file
+func (d *DedupIndex) Path() string {
+    return d.path
+}
+
+// Contains returns true if the identifier is in the index
+func (d *DedupIndex) Contains(key []byte) (bool, error) {
+    if d.added[string(key)] {
+        return true, nil
+    }
+    if d.count == 0 {
+        return false, nil
+    }
+    // the block which may hold the key
+    blocks := len(d.fences) / d.keySize
+    b := sort.Search(blocks, func(i int) bool {
+        return bytes.Compare(d.fences[i * d.keySize:(i + 1) * d.keySize], key) > 0
+    }) - 1
+    if b < 0 {
+        return false, nil
+    }
+    n := d.count - int64(b) * dedupBlockSize
+    if n > dedupBlockSize {
+        n = dedupBlockSize
+    }
+    block := make([]byte, int(n) * d.keySize)
+    if _, err := d.file.ReadAt(block, int64(dedupHeaderSize) + int64(b) * dedupBlockSize * int64(d.keySize)); err != nil {
+        return false, fmt.Errorf("%v: %v", d.path, err)
+    }
+    i := sort.Search(int(n), func(i int) bool {
+        return bytes.Compare(block[i * d.keySize:(i + 1) * d.keySize], key) >= 0
+    })
+    return i < int(n) && bytes.Equal(block[i * d.keySize:(i + 1) * d.keySize], key), nil
+}
+
+// Add adds the identifier to the index. It returns false if it was already in it.
+func (d *DedupIndex) Add(key []byte) (bool, error) {
+    if len(key) != d.keySize {
+        return false, fmt.Errorf("Expected an identifier of %v bytes, got %v", d.keySize, len(key))
+    }
+    ok, err := d.Contains(key)
+    if err != nil || ok {
+        return false, err
+    }
+    d.added[string(key)] = true
+    return true, nil
+}
+
+// Close merges the added identifiers into the index file, which is replaced
+// once fully written.
+func (d *DedupIndex) Close() error {
+    if d.file != nil {
+        defer d.file.Close()
+    }
+    if len(d.added) == 0 {
+        return nil
+    }
+    added := make([]string, 0, len(d.added))
+    for key := range d.added {
+        added = append(added, key)
+    }
+    sort.Strings(added)
+
+    tmp := d.path + ".tmp"
+    fo, err := os.Create(tmp)
+    if err != nil {
+        return err
+    }
+    if err := d.merge(fo, added); err != nil {
+        fo.Close()
+        os.Remove(tmp)
+        return err
+    }
+    if err := fo.Close(); err != nil {
+        os.Remove(tmp)
+        return err
+    }
+    return os.Rename(tmp, d.path)
+}
+
+// merge writes the identifiers of the file and the added ones, in order
+func (d *DedupIndex) merge(fo *os.File, added []string) error {
+    w := bufio.NewWriter(fo)
+    header := make([]byte, dedupHeaderSize)
+    copy(header, dedupMagic)
+    binary.BigEndian.PutUint32(header[len(dedupMagic):], uint32(d.keySize))
+    binary.BigEndian.PutUint64(header[len(dedupMagic) + 4:], uint64(d.Len()))
+    w.Write(header)
+    if d.file != nil {
+        r := bufio.NewReader(io.NewSectionReader(d.file, int64(dedupHeaderSize), d.count * int64(d.keySize)))
+        key := make([]byte, d.keySize)
+        for i := int64(0); i < d.count; i++ {
+            if _, err := io.ReadFull(r, key); err != nil {
+                return fmt.Errorf("%v: %v", d.path, err)
+            }
+            for len(added) != 0 && added[0] < string(key) {
+                w.WriteString(added[0])
+                added = added[1:]
+            }
+            w.Write(key)
+        }
+    }
+    for _, key := range added {
+        w.WriteString(key)
+    }
+    if err := w.Flush(); err != nil {
+        return err
+    }
+    return fo.Sync()
+}
+
+// ResetDedupIndex removes the dedup index file at path
+func ResetDedupIndex(path string) error {
+    if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+        return err
+    }
+    return nil
+}
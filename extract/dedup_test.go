@@ -0,0 +1,235 @@
+package extract
+
+import (
+    "encoding/binary"
+    "encoding/csv"
+    "io/ioutil"
+    "os"
+    "path"
+    "strings"
+    "testing"
+)
+
+// dedupKey returns the identifier of n
+func dedupKey(n uint64) []byte {
+    key := make([]byte, componentIDSize)
+    binary.BigEndian.PutUint64(key[componentIDSize - 8:], n)
+    return key
+}
+
+func TestDedupIndex(t *testing.T) {
+    dir, err := ioutil.TempDir("", "dedup")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+    file := path.Join(dir, "index")
+
+    // the even numbers in a first run, spanning several blocks
+    d, err := OpenDedupIndex(file)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for n := uint64(0); n < 3 * dedupBlockSize; n += 2 {
+        if ok, err := d.Add(dedupKey(n * 1000)); err != nil || !ok {
+            t.Fatalf("Expected %v to be added, got %v %v", n, ok, err)
+        }
+    }
+    if ok, _ := d.Add(dedupKey(0)); ok {
+        t.Error("Expected 0 to be in the index")
+    }
+    if err := d.Close(); err != nil {
+        t.Fatal(err)
+    }
+
+    // every number in a second run
+    d, err = OpenDedupIndex(file)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if d.Len() != 3 * dedupBlockSize / 2 {
+        t.Errorf("Expected %v identifiers, got %v", 3 * dedupBlockSize / 2, d.Len())
+    }
+    for n := uint64(0); n < 3 * dedupBlockSize; n++ {
+        ok, err := d.Add(dedupKey(n * 1000))
+        if err != nil {
+            t.Fatal(err)
+        }
+        if ok != (n % 2 == 1) {
+            t.Errorf("Unexpected addition of %v: %v", n, ok)
+        }
+    }
+    if err := d.Close(); err != nil {
+        t.Fatal(err)
+    }
+
+    d, err = OpenDedupIndex(file)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer d.Close()
+    if d.Len() != 3 * dedupBlockSize {
+        t.Errorf("Expected %v identifiers, got %v", 3 * dedupBlockSize, d.Len())
+    }
+    for n := uint64(0); n < 3 * dedupBlockSize; n++ {
+        if ok, err := d.Contains(dedupKey(n * 1000)); err != nil || !ok {
+            t.Errorf("Expected %v in the index, got %v %v", n, ok, err)
+        }
+        if ok, err := d.Contains(dedupKey(n * 1000 + 1)); err != nil || ok {
+            t.Errorf("Unexpected %v in the index, got %v %v", n * 1000 + 1, ok, err)
+        }
+    }
+}
+
+func TestDedupIndexKeySize(t *testing.T) {
+    dir, err := ioutil.TempDir("", "dedup")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+    file := path.Join(dir, "index")
+
+    d, err := openDedupIndex(file, 4)
+    if err != nil {
+        t.Fatal(err)
+    }
+    d.Add([]byte("abcd"))
+    if err := d.Close(); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := OpenDedupIndex(file); err == nil || !strings.Contains(err.Error(), "must be reset") {
+        t.Errorf("Expected an error on the identifier size, got %v", err)
+    }
+}
+
+func TestExtractDedup(t *testing.T) {
+    input, err := ioutil.TempDir("", "input")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(input)
+    lines := strings.SplitAfter(tomcatLog, "\n")
+    index := path.Join(input, "index")
+
+    // the first day
+    if err := ioutil.WriteFile(path.Join(input, "day1.log"), []byte(lines[0]), 0644); err != nil {
+        t.Fatal(err)
+    }
+    output1, err := ioutil.TempDir("", "output")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(output1)
+    opts := &Options{ Include : []string{ "*.log" }, Dedup : index }
+    if _, err := Extract(TOMCAT, input, output1, opts); err != nil {
+        t.Fatal(err)
+    }
+    if actual := readOutput(t, output1); len(actual) != 1 || actual["query_2.gz"] == "" {
+        t.Errorf("Unexpected output %v", actual)
+    }
+
+    // the second day repeats the component of the first
+    if err := ioutil.WriteFile(path.Join(input, "day2.log"), []byte(lines[2] + lines[3]), 0644); err != nil {
+        t.Fatal(err)
+    }
+    output2, err := ioutil.TempDir("", "output")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(output2)
+    summary, err := Extract(TOMCAT, input, output2, opts)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if actual := readOutput(t, output2); len(actual) != 1 || actual["query_1-1.gz"] == "" || summary.Components != 1 {
+        t.Errorf("Expected only the new component, got %v", actual)
+    }
+    d, err := OpenDedupIndex(index)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer d.Close()
+    if d.Len() != 2 {
+        t.Errorf("Expected 2 components in the index, got %v", d.Len())
+    }
+}
+
+func TestExtractDedupSameOutput(t *testing.T) {
+    input, err := ioutil.TempDir("", "input")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(input)
+    output, err := ioutil.TempDir("", "output")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(output)
+    lines := strings.SplitAfter(tomcatLog, "\n")
+    index := path.Join(input, "index")
+    opts := &Options{ Include : []string{ "*.log" }, Dedup : index, OutputFormat : CSV }
+
+    // the two runs write new components of complexity 2 into the same output folder
+    if err := ioutil.WriteFile(path.Join(input, "day1.log"), []byte(lines[0]), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := Extract(TOMCAT, input, output, opts); err != nil {
+        t.Fatal(err)
+    }
+    if err := ioutil.WriteFile(path.Join(input, "day1.log"), []byte(strings.Replace(lines[0], "%3CC%3E", "%3CD%3E", 1) + lines[2]), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := Extract(TOMCAT, input, output, opts); err != nil {
+        t.Fatal(err)
+    }
+    actual := readOutput(t, output)
+    if len(actual) != 2 {
+        t.Fatalf("Expected the components of both runs, got %v", actual)
+    }
+    for name, records := range map[string]int{ "query_2.csv.gz" : 2, "query_1-1.csv.gz" : 1 } {
+        rows, err := csv.NewReader(strings.NewReader(actual[name])).ReadAll()
+        if err != nil {
+            t.Fatal(err)
+        }
+        if len(rows) != records + 1 || rows[0][0] != "id" {
+            t.Errorf("Expected a header and %v records in %v, got %v", records, name, rows)
+        }
+    }
+}
+
+func TestExtractDedupReset(t *testing.T) {
+    input, err := ioutil.TempDir("", "input")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(input)
+    output, err := ioutil.TempDir("", "output")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(output)
+    lines := strings.SplitAfter(tomcatLog, "\n")
+    index := path.Join(input, "index")
+    if err := ioutil.WriteFile(path.Join(input, "day1.log"), []byte(lines[2]), 0644); err != nil {
+        t.Fatal(err)
+    }
+    opts := &Options{ Include : []string{ "*.log" }, Dedup : index }
+
+    // the run after a reset rewrites the output of the first
+    if _, err := Extract(TOMCAT, input, output, opts); err != nil {
+        t.Fatal(err)
+    }
+    if err := ResetDedupIndex(index); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := Extract(TOMCAT, input, output, opts); err != nil {
+        t.Fatal(err)
+    }
+    content := readFiles(t, output)
+    if n := strings.Count(content["query_1-1.gz"], "# id: "); n != 1 {
+        t.Errorf("Expected the component once, got %q", content["query_1-1.gz"])
+    }
+    if !strings.Contains(content[statsFile], "\t1-1\t1\t1\t") {
+        t.Errorf("Expected a single occurrence, got %q", content[statsFile])
+    }
+}
@@ -0,0 +1,127 @@
+package extract
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+)
+
+// ErrorPolicy is what the extraction does with a log that cannot be read,
+// or with a log entry whose query cannot be decoded
+type ErrorPolicy string
+
+const (
+    // Abort stops the extraction at the first error, which is the default
+    Abort ErrorPolicy = "abort"
+    // Skip skips the rest of the log, or the entry, and counts it in the summary
+    Skip ErrorPolicy = "skip"
+    // Collect skips like Skip, and keeps the errors in the summary
+    Collect ErrorPolicy = "collect"
+)
+
+func (ep ErrorPolicy) String() string {
+    return string(ep)
+}
+
+// Set method needed for the flag package
+func (ep *ErrorPolicy) Set(s string) error {
+    switch policy := ErrorPolicy(strings.ToLower(s)); policy {
+    case Abort, Skip, Collect:
+        *ep = policy
+        return nil
+    }
+    return fmt.Errorf("Unknown error policy: [%v], expected %v, %v or %v", s, Abort, Skip, Collect)
+}
+
+// FileError is an error reading a log, e.g., a file that cannot be opened or
+// whose compression is corrupted. Path is the path of the file, or of the
+// entry of an archive.
+type FileError struct {
+    Path string
+    Err error
+}
+
+func (e *FileError) Error() string {
+    return e.Path + ": " + e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+    return e.Err
+}
+
+// EntryError is an error decoding the query of a log entry. Line is the line
+// of the log the entry starts on, starting at 1, and Data is the entry.
+type EntryError struct {
+    Log string
+    Line int
+    Data []byte
+    Err error
+}
+
+func (e *EntryError) Error() string {
+    return fmt.Sprintf("%v:%v: %v", e.Log, e.Line, e.Err)
+}
+
+func (e *EntryError) Unwrap() error {
+    return e.Err
+}
+
+// OutputError is an error writing the output file at Path.
+// It always stops the extraction, whatever the ErrorPolicy.
+type OutputError struct {
+    Path string
+    Err error
+}
+
+func (e *OutputError) Error() string {
+    return e.Path + ": " + e.Err.Error()
+}
+
+func (e *OutputError) Unwrap() error {
+    return e.Err
+}
+
+// errAborted stops reading the logs once the extraction is aborted
+var errAborted = errors.New("extract: aborted")
+
+// Summary reports on an extraction
+type Summary struct {
+    // Logs is the number of logs read entirely, counting each entry of an archive
+    Logs int
+    // Queries is the number of queries read
+    Queries int
+    // Unparsed is the number of queries the parser failed on
+    Unparsed int
+    // Quarantined is the number of queries which could not be decoded or
+    // parsed, written to the quarantine file
+    Quarantined int
+    // Components is the number of distinct connected components written
+    Components int
+    // SkippedLogs is the number of logs which could not be read entirely
+    SkippedLogs int
+    // SkippedEntries is the number of entries whose query could not be decoded
+    SkippedEntries int
+    // Errors holds the FileError and EntryError of the skipped logs and
+    // entries with the Collect policy
+    Errors []error
+}
+
+// skip applies the policy to the error of a log or of an entry.
+// It returns the error if the extraction must stop.
+func (s *Summary) skip(policy ErrorPolicy, err error) error {
+    if policy != Skip && policy != Collect {
+        return err
+    }
+    switch err.(type) {
+    case *FileError:
+        s.SkippedLogs++
+    case *EntryError:
+        s.SkippedEntries++
+    default:
+        return err
+    }
+    if policy == Collect {
+        s.Errors = append(s.Errors, err)
+    }
+    return nil
+}
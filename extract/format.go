@@ -0,0 +1,231 @@
+package extract
+
+import (
+    "bufio"
+    "bytes"
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Format describes how SPARQL queries are read from a log.
+type Format struct {
+    // Split is a bufio.SplitFunc returning the next log entry that holds
+    // a SPARQL query. Entries without a query are skipped, or have an empty
+    // query.
+    Split bufio.SplitFunc
+    // NewSplit returns a new split function for each log. It replaces Split
+    // for formats that keep state between entries, e.g., to join the lines
+    // of a request.
+    NewSplit func() bufio.SplitFunc
+    // Query returns the decoded SPARQL query of an entry returned by Split.
+    Query func(entry []byte) (string, error)
+    // Metadata returns information about an entry returned by Split.
+    // It may be nil if the format carries no metadata.
+    Metadata func(entry []byte) Metadata
+    // Entry returns the query and the metadata of an entry returned by Split
+    // in one call. It replaces Query and Metadata for formats which parse the
+    // whole entry to get either.
+    Entry func(entry []byte) (*Entry, error)
+    // Configure returns a variant of the format set up with the options given
+    // after the name of the format, e.g., "json:query=params.query".
+    // It may be nil if the format takes no option.
+    Configure func(options string) (*Format, error)
+}
+
+// Metadata holds information about the log entry a query was found in.
+// Fields the log format does not provide are left to their zero value.
+type Metadata struct {
+    // Client is the address of the host which sent the query
+    Client string
+    // Ident is the RFC 1413 identity of the client
+    Ident string
+    // User is the authenticated user
+    User string
+    // Time is when the query was received
+    Time time.Time
+    // Method, Path and Protocol are the parts of the HTTP request line
+    Method, Path, Protocol string
+    // Status is the HTTP status code of the response
+    Status int
+    // Bytes is the size of the response
+    Bytes int64
+    // Referer is the Referer HTTP request header
+    Referer string
+    // UserAgent is the User-Agent HTTP request header
+    UserAgent string
+    // Host is the endpoint which received the query
+    Host string
+    // RequestID identifies the request in the endpoint's log
+    RequestID string
+    // DefaultGraphs and NamedGraphs are the RDF dataset of the query, given
+    // by the default-graph-uri and named-graph-uri parameters of the request
+    DefaultGraphs, NamedGraphs []string
+}
+
+// Entry is a SPARQL query read from a log
+type Entry struct {
+    Query string
+    Metadata
+}
+
+var (
+    formatsMu sync.RWMutex
+    formats = make(map[string]*Format)
+)
+
+// RegisterFormat makes a log format available under the given name.
+// Names are case insensitive. It panics if the name is already registered,
+// or if the format has no split, Query or Entry function.
+func RegisterFormat(name string, f *Format) {
+    formatsMu.Lock()
+    defer formatsMu.Unlock()
+    name = strings.ToLower(name)
+    if f == nil || (f.Split == nil && f.NewSplit == nil) || (f.Query == nil && f.Entry == nil) {
+        panic("extract: RegisterFormat " + name + " is incomplete")
+    }
+    if _, dup := formats[name]; dup {
+        panic("extract: RegisterFormat called twice for " + name)
+    }
+    formats[name] = f
+}
+
+// Formats returns the sorted names of the registered log formats.
+func Formats() []string {
+    formatsMu.RLock()
+    defer formatsMu.RUnlock()
+    var names []string
+    for name := range formats {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// lookupFormat returns the format registered with the given name
+func lookupFormat(name string) (*Format, bool) {
+    formatsMu.RLock()
+    defer formatsMu.RUnlock()
+    f, ok := formats[strings.ToLower(name)]
+    return f, ok
+}
+
+// split returns the split function to read a log with
+func (f *Format) split() bufio.SplitFunc {
+    if f.NewSplit != nil {
+        return f.NewSplit()
+    }
+    return f.Split
+}
+
+// scanRecord returns the next record of a log where a record spans several
+// lines: a line for which isStart is true, followed by continuation lines.
+// Lines before the first record are skipped.
+func scanRecord(data []byte, atEOF bool, isStart func(line []byte) bool) (advance int, record []byte, err error) {
+    start, end := -1, 0
+    for end < len(data) {
+        next := len(data)
+        if ind := bytes.IndexByte(data[end:], '\n'); ind != -1 {
+            next = end + ind + 1
+        } else if !atEOF {
+            break
+        }
+        if isStart(dropCR(bytes.TrimSuffix(data[end:next], []byte("\n")))) {
+            if start != -1 {
+                return end, trimRecord(data[start:end]), nil
+            }
+            start = end
+        }
+        end = next
+    }
+    switch {
+    case start == -1:
+        // skip the lines read so far
+        return end, nil, nil
+    case atEOF:
+        return len(data), trimRecord(data[start:]), nil
+    default:
+        // wait for the start of the next record
+        return start, nil, nil
+    }
+}
+
+// trimRecord removes the line terminator at the end of the record
+func trimRecord(record []byte) []byte {
+    return dropCR(bytes.TrimSuffix(record, []byte("\n")))
+}
+
+// dropCR drops a terminal \r from the data
+func dropCR(data []byte) []byte {
+    if len(data) > 0 && data[len(data)-1] == '\r' {
+        return data[:len(data)-1]
+    }
+    return data
+}
+
+// LogFormat is the name of a registered log format, optionally followed by
+// a colon and the options of the format.
+//
+// The query logs of Virtuoso and Blazegraph are not read yet: their formats
+// are left to a follow-up, to be built against sample logs of the servers.
+type LogFormat string
+
+const (
+    // Apache Combined Log Format http://httpd.apache.org/docs/current/logs.html
+    TOMCAT LogFormat = "tomcat"
+    // Common or Combined Log Format with every field parsed
+    COMBINED LogFormat = "combined"
+    // Apache Jena Fuseki server log
+    FUSEKI LogFormat = "fuseki"
+    // JSON objects, one per line
+    JSON LogFormat = "json"
+    // ModSecurity audit log, with the request bodies
+    MODSECURITY LogFormat = "modsecurity"
+)
+
+func (lf LogFormat) String() string {
+    return string(lf)
+}
+
+// Set method needed for the flag package
+func (lf *LogFormat) Set(s string) error {
+    name, options := splitLogFormat(s)
+    if _, ok := lookupFormat(name); !ok {
+        return fmt.Errorf("Unknown log format: [%v], expected one of %v", name, Formats())
+    }
+    parsed := LogFormat(strings.ToLower(name))
+    if options != "" {
+        parsed += LogFormat(":" + options)
+    }
+    if _, err := parsed.format(); err != nil {
+        return err
+    }
+    *lf = parsed
+    return nil
+}
+
+// splitLogFormat returns the name and the options of the log format
+func splitLogFormat(s string) (name, options string) {
+    if ind := strings.IndexByte(s, ':'); ind != -1 {
+        return s[:ind], s[ind+1:]
+    }
+    return s, ""
+}
+
+// format returns the Format registered under that name, configured with the options
+func (lf LogFormat) format() (*Format, error) {
+    name, options := splitLogFormat(string(lf))
+    f, ok := lookupFormat(name)
+    if !ok {
+        return nil, fmt.Errorf("Unknown log format: [%v]", name)
+    }
+    if options == "" {
+        return f, nil
+    }
+    if f.Configure == nil {
+        return nil, fmt.Errorf("The log format [%v] takes no option: [%v]", name, options)
+    }
+    return f.Configure(options)
+}
@@ -0,0 +1,226 @@
+package extract
+
+import (
+    "bufio"
+    "reflect"
+    "strings"
+    "testing"
+    "testing/iotest"
+)
+
+// scanEntries returns the entries read from the log with the given format.
+// The log is read one byte at a time so that entries span several reads.
+func scanEntries(t *testing.T, lf LogFormat, log string) []*Entry {
+    format, err := lf.format()
+    if err != nil {
+        t.Fatal(err)
+    }
+    var entries []*Entry
+    s := bufio.NewScanner(iotest.OneByteReader(strings.NewReader(log)))
+    s.Split(format.split())
+    for s.Scan() {
+        entry, err := newEntry(format, s.Bytes())
+        if err != nil {
+            t.Fatal(err)
+        }
+        entries = append(entries, entry)
+    }
+    if s.Err() != nil {
+        t.Fatal(s.Err())
+    }
+    return entries
+}
+
+func TestFusekiInterleaved(t *testing.T) {
+    log := `[2016-10-05 10:13:14] Server     INFO  Started
+[2016-10-05 10:13:14] Fuseki     INFO  [12] GET http://localhost:3030/ds/query?query=SELECT...
+[2016-10-05 10:13:14] Fuseki     INFO  [13] POST http://example.org:3030/ds/sparql
+[2016-10-05 10:13:15] Fuseki     INFO  [13] Query = ASK { ?s ?p ?o }
+[2016-10-05 10:13:15] Fuseki     INFO  [12] Query = SELECT *
+WHERE {
+  ?s ?p ?o
+}
+LIMIT 10
+[2016-10-05 10:13:15] Fuseki     INFO  [13] 200 OK (2 ms)
+[2016-10-05 10:13:15] Fuseki     INFO  [12] 200 OK (5 ms)
+[2016-10-05 10:13:16] Fuseki     INFO  [14] Query = DESCRIBE <x>`
+    entries := scanEntries(t, FUSEKI, log)
+    if len(entries) != 3 {
+        t.Fatalf("Expected 3 entries, but got %v", len(entries))
+    }
+    expected := []struct{ query, id, method, host, path string }{
+        { "ASK { ?s ?p ?o }", "13", "POST", "example.org:3030", "/ds/sparql" },
+        { "SELECT *\nWHERE {\n  ?s ?p ?o\n}\nLIMIT 10", "12", "GET", "localhost:3030", "/ds/query" },
+        { "DESCRIBE <x>", "14", "", "", "" },
+    }
+    for i, e := range expected {
+        actual := entries[i]
+        if actual.Query != e.query || actual.RequestID != e.id || actual.Method != e.method || actual.Host != e.host || actual.Path != e.path {
+            t.Errorf("Expected %+v, but got %+v", e, actual)
+        }
+        if actual.Time.IsZero() {
+            t.Errorf("Missing time in %+v", actual)
+        }
+    }
+}
+
+func TestFusekiQueryRecord(t *testing.T) {
+    // the request record spans two lines
+    entry := `[2016-10-05 10:13:14] Fuseki     INFO  [12] POST http://localhost:3030/ds/sparql
+  Content-Type: application/sparql-query
+[2016-10-05 10:13:15] Fuseki     INFO  [12] Query = ASK { ?s ?p ?o }`
+    query, err := fusekiQuery([]byte(entry))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if expected := "ASK { ?s ?p ?o }"; query != expected {
+        t.Errorf("Expected %q, but got %q", expected, query)
+    }
+    if _, err := fusekiQuery([]byte(entry[:strings.LastIndex(entry, "\n")])); err == nil {
+        t.Errorf("Expected an error without the Query record")
+    }
+}
+
+func TestJSONFieldPaths(t *testing.T) {
+    log := `{"ts":"2016-10-05T10:13:14Z","remote":{"addr":"10.0.0.2"},"request":{"params":{"query":"ASK {}"}},"response":{"status":200}}
+
+{"ts":1475662394,"request":{"params":{}}}
+{"ts":"2016-10-05T10:13:15Z","remote":{"addr":"10.0.0.3"},"request":{"params":{"query":"DESCRIBE <x>"}},"response":{"status":"500"}}
+`
+    var lf LogFormat
+    if err := lf.Set("JSON:query=request.params.query,time=ts,client=remote.addr,status=response.status"); err != nil {
+        t.Fatal(err)
+    }
+    var queries []*Entry
+    for _, e := range scanEntries(t, lf, log) {
+        if e.Query != "" {
+            queries = append(queries, e)
+        }
+    }
+    if len(queries) != 2 {
+        t.Fatalf("Expected 2 queries, but got %v", len(queries))
+    }
+    if e := queries[0]; e.Query != "ASK {}" || e.Client != "10.0.0.2" || e.Status != 200 || e.Time.Unix() != 1475662394 {
+        t.Errorf("Unexpected entry %+v", e)
+    }
+    if e := queries[1]; e.Query != "DESCRIBE <x>" || e.Client != "10.0.0.3" || e.Status != 500 {
+        t.Errorf("Unexpected entry %+v", e)
+    }
+    if err := lf.Set("json:querry=q"); err == nil {
+        t.Errorf("Expected an error for an unknown field")
+    }
+    if err := lf.Set("tomcat:x"); err == nil {
+        t.Errorf("Expected an error for an option to tomcat")
+    }
+}
+
+func TestModsecurity(t *testing.T) {
+    log := `--a1b2c3d4-A--
+[05/Oct/2016:10:13:14 +0200] V9SrBn8AAQEAAB 10.0.0.2 51234 10.0.0.1 80
+--a1b2c3d4-B--
+POST /sparql HTTP/1.1
+Host: dbpedia.org
+Content-Type: application/x-www-form-urlencoded; charset=UTF-8
+
+--a1b2c3d4-C--
+query=SELECT+*+%7B+%3Fs+%3Fp+%3Fo+%7D&default-graph-uri=http%3A%2F%2Fdbpedia.org&named-graph-uri=g1&named-graph-uri=g2
+--a1b2c3d4-F--
+HTTP/1.1 200 OK
+
+--a1b2c3d4-Z--
+
+--b1b2c3d4-A--
+[05/Oct/2016:10:13:15 +0200] V9SrBn8AAQEAAC 10.0.0.3 51235 10.0.0.1 80
+--b1b2c3d4-B--
+GET /index.html HTTP/1.1
+Host: dbpedia.org
+
+--b1b2c3d4-F--
+HTTP/1.1 200 OK
+
+--b1b2c3d4-Z--
+--c1b2c3d4-A--
+[05/Oct/2016:10:13:16 +0200] V9SrBn8AAQEAAD 10.0.0.4 51236 10.0.0.1 80
+--c1b2c3d4-B--
+POST /sparql?default-graph-uri=http%3A%2F%2Fdbpedia.org HTTP/1.1
+Content-Type: application/sparql-query
+
+--c1b2c3d4-C--
+ASK {
+  ?s ?p ?o
+}
+--c1b2c3d4-F--
+HTTP/1.1 400 Bad Request
+
+--c1b2c3d4-Z--
+`
+    entries := scanEntries(t, MODSECURITY, log)
+    if len(entries) != 2 {
+        t.Fatalf("Expected 2 entries, but got %v", len(entries))
+    }
+    e := entries[0]
+    if e.Query != "SELECT * { ?s ?p ?o }" || e.Client != "10.0.0.2" || e.Host != "dbpedia.org" || e.Status != 200 || e.RequestID != "V9SrBn8AAQEAAB" || e.Path != "/sparql" {
+        t.Errorf("Unexpected entry %+v", e)
+    }
+    if !reflect.DeepEqual(e.DefaultGraphs, []string{ "http://dbpedia.org" }) || !reflect.DeepEqual(e.NamedGraphs, []string{ "g1", "g2" }) {
+        t.Errorf("Unexpected dataset %v %v", e.DefaultGraphs, e.NamedGraphs)
+    }
+    e = entries[1]
+    if e.Query != "ASK {\n  ?s ?p ?o\n}" || e.Status != 400 || !reflect.DeepEqual(e.DefaultGraphs, []string{ "http://dbpedia.org" }) {
+        t.Errorf("Unexpected entry %+v", e)
+    }
+}
+
+func TestJSONBody(t *testing.T) {
+    log := `{"body":"query=ASK+%7B%7D&named-graph-uri=g1","content_type":"application/x-www-form-urlencoded"}
+{"body":"DESCRIBE <x>","content_type":"application/sparql-query"}
+{"body":"INSERT DATA {}","content_type":"application/sparql-update"}
+`
+    entries := scanEntries(t, JSON, log)
+    if len(entries) != 3 {
+        t.Fatalf("Expected 3 entries, but got %v", len(entries))
+    }
+    if e := entries[0]; e.Query != "ASK {}" || !reflect.DeepEqual(e.NamedGraphs, []string{ "g1" }) {
+        t.Errorf("Unexpected entry %+v", e)
+    }
+    if e := entries[1]; e.Query != "DESCRIBE <x>" {
+        t.Errorf("Unexpected entry %+v", e)
+    }
+    if e := entries[2]; e.Query != "" {
+        t.Errorf("Unexpected entry %+v", e)
+    }
+}
+
+func TestTomcatWholeQuery(t *testing.T) {
+    log := `127.0.0.1 - - [10/Oct/2015:13:55:36 -0700] "GET /sparql?default-graph-uri=http%3A%2F%2Fdbpedia.org&query=select+*+%7B+%3Fs+%3Fp+%3Fo+%7D+ORDER+BY+%3Fs+LIMIT+10&format=json HTTP/1.1" 200 2326
+127.0.0.1 - - [10/Oct/2015:13:55:37 -0700] "GET /index.html?xquery=1 HTTP/1.1" 200 10
+127.0.0.1 - - [10/Oct/2015:13:55:38 -0700] "GET /sparql?query=DESCRIBE+%3Chttp%3A%2F%2Fex.org%2Fx%3E HTTP/1.1" 200 10
+`
+    entries := scanEntries(t, TOMCAT, log)
+    if len(entries) != 2 {
+        t.Fatalf("Expected 2 entries, but got %v", len(entries))
+    }
+    if expected := "select * { ?s ?p ?o } ORDER BY ?s LIMIT 10"; entries[0].Query != expected {
+        t.Errorf("Expected %q, but got %q", expected, entries[0].Query)
+    }
+    if expected := "DESCRIBE <http://ex.org/x>"; entries[1].Query != expected {
+        t.Errorf("Expected %q, but got %q", expected, entries[1].Query)
+    }
+}
+
+func TestTomcatMetadata(t *testing.T) {
+    log := `10.0.0.2 - - [10/Oct/2015:13:56:00 -0700] "GET /sparql?query=ASK+%7B%7D HTTP/1.1" 200 12
+GET /sparql?query=ASK+%7B%7D
+`
+    entries := scanEntries(t, TOMCAT, log)
+    if len(entries) != 2 {
+        t.Fatalf("Expected 2 entries, but got %v", len(entries))
+    }
+    if e := entries[0]; e.Client != "10.0.0.2" || e.Status != 200 || e.Time.Format(clfTimeLayout) != "10/Oct/2015:13:56:00 -0700" {
+        t.Errorf("Unexpected entry %+v", e)
+    }
+    // the line is not in the Common Log Format
+    if e := entries[1]; e.Query != "ASK {}" || !e.Time.IsZero() || e.Client != "" {
+        t.Errorf("Unexpected entry %+v", e)
+    }
+}
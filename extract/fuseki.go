@@ -0,0 +1,136 @@
+package extract
+
+import (
+    "bufio"
+    "bytes"
+    "fmt"
+    "net/url"
+    "regexp"
+    "strings"
+    "time"
+)
+
+func init() {
+    RegisterFormat(string(FUSEKI), &Format{
+        NewSplit: newFusekiSplit,
+        Query: fusekiQuery,
+        Metadata: fusekiMetadata,
+    })
+}
+
+// The Fuseki server log, where the lines of a request share an identifier:
+//   [2016-10-05 10:13:14] Fuseki     INFO  [12] GET http://localhost:3030/ds/query?query=...
+//   [2016-10-05 10:13:14] Fuseki     INFO  [12] Query = SELECT *
+//   WHERE { ?s ?p ?o }
+//   [2016-10-05 10:13:14] Fuseki     INFO  [12] 200 OK (5 ms)
+// The query record continues on the lines until the next log record.
+var fusekiReg *regexp.Regexp = regexp.MustCompile(`^\[(\d{4}-\d\d-\d\d \d\d:\d\d:\d\d)\]\s+\S+\s+[A-Z]+\s+\[(\d+)\]\s+`)
+
+// The layout of the Fuseki timestamp
+const fusekiTimeLayout = "2006-01-02 15:04:05"
+
+// The prefix of the message holding the query
+const fusekiQueryPrefix = "Query = "
+
+// isFusekiRecord returns true if the line starts a log record
+func isFusekiRecord(line []byte) bool {
+    return fusekiReg.Match(line)
+}
+
+// fusekiRecord splits a Fuseki log record into its timestamp, request identifier and message
+func fusekiRecord(record []byte) (ts, id, msg string, ok bool) {
+    m := fusekiReg.FindSubmatchIndex(record)
+    if m == nil {
+        return "", "", "", false
+    }
+    return string(record[m[2]:m[3]]), string(record[m[4]:m[5]]), string(record[m[1]:]), true
+}
+
+// newFusekiSplit returns a split function joining the request record with the
+// query record of the same identifier. The token is the request record
+// followed by the query record, if the request was logged.
+func newFusekiSplit() bufio.SplitFunc {
+    // the request records waiting for their query, by identifier
+    requests := make(map[string][]byte)
+    return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+        skipped := 0
+        for {
+            advance, token, err = scanRecord(data[skipped:], atEOF, isFusekiRecord)
+            if err != nil || advance == 0 {
+                return skipped, nil, err
+            }
+            skipped += advance
+            if token == nil {
+                continue
+            }
+            _, id, msg, ok := fusekiRecord(token)
+            if !ok {
+                continue
+            }
+            switch {
+            case strings.HasPrefix(msg, fusekiQueryPrefix):
+                entry := append(requests[id], token...)
+                delete(requests, id)
+                return skipped, entry, nil
+            case strings.HasPrefix(msg, "GET ") || strings.HasPrefix(msg, "POST "):
+                requests[id] = append(append([]byte{}, token...), '\n')
+            default:
+                if fusekiStatus(msg) != 0 {
+                    // the request is over
+                    delete(requests, id)
+                }
+            }
+        }
+    }
+}
+
+// fusekiStatus returns the HTTP status of a record such as "200 OK (5 ms)", or 0
+func fusekiStatus(msg string) int {
+    var status int
+    if _, err := fmt.Sscanf(msg, "%d ", &status); err != nil || status < 100 || status > 599 {
+        return 0
+    }
+    return status
+}
+
+// fusekiQuery returns the query of the Query record, which runs to the end of
+// the entry
+func fusekiQuery(entry []byte) (string, error) {
+    for start := 0; start < len(entry); {
+        if _, _, msg, ok := fusekiRecord(entry[start:]); ok && strings.HasPrefix(msg, fusekiQueryPrefix) {
+            return msg[len(fusekiQueryPrefix):], nil
+        }
+        next := bytes.IndexByte(entry[start:], '\n')
+        if next == -1 {
+            break
+        }
+        start += next + 1
+    }
+    return "", fmt.Errorf("No query in [%s]", entry)
+}
+
+// fusekiMetadata returns the time, request identifier and URL of the request
+func fusekiMetadata(entry []byte) Metadata {
+    var m Metadata
+    ts, id, msg, ok := fusekiRecord(entry)
+    if !ok {
+        return m
+    }
+    m.RequestID = id
+    if t, err := time.ParseInLocation(fusekiTimeLayout, ts, time.Local); err == nil {
+        m.Time = t
+    }
+    if strings.HasPrefix(msg, fusekiQueryPrefix) {
+        return m
+    }
+    // the request record
+    parts := strings.Fields(msg)
+    m.Method = parts[0]
+    if len(parts) > 1 {
+        if u, err := url.Parse(parts[1]); err == nil {
+            m.Host = u.Host
+            m.Path = u.Path
+        }
+    }
+    return m
+}
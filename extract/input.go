@@ -0,0 +1,256 @@
+package extract
+
+import (
+    "archive/tar"
+    "archive/zip"
+    "fmt"
+    "io"
+    "os"
+    "path"
+    "path/filepath"
+    "sort"
+    "strings"
+)
+
+// FileOrder is the order in which the log files are processed
+type FileOrder string
+
+const (
+    // ByName orders the files by their path relative to the input folder
+    ByName FileOrder = "name"
+    // ByMtime orders the files by modification time, then by name
+    ByMtime FileOrder = "mtime"
+)
+
+func (fo FileOrder) String() string {
+    return string(fo)
+}
+
+// Set method needed for the flag package
+func (fo *FileOrder) Set(s string) error {
+    switch order := FileOrder(strings.ToLower(s)); order {
+    case ByName, ByMtime:
+        *fo = order
+        return nil
+    }
+    return fmt.Errorf("Unknown file order: [%v], expected %v or %v", s, ByName, ByMtime)
+}
+
+// logFile is a log file found in the input folder
+type logFile struct {
+    // path of the file
+    path string
+    // rel is the slash-separated path relative to the input folder
+    rel string
+    info os.FileInfo
+}
+
+// checkPatterns returns an error if one of the glob patterns is malformed
+func checkPatterns(patterns []string) error {
+    for _, pattern := range patterns {
+        if _, err := path.Match(pattern, ""); err != nil {
+            return fmt.Errorf("Bad pattern [%v]: %v", pattern, err)
+        }
+    }
+    return nil
+}
+
+// matchAny returns true if the relative path or its base name matches one of
+// the glob patterns, which are checked with checkPatterns
+func matchAny(patterns []string, rel string) bool {
+    for _, pattern := range patterns {
+        for _, name := range []string{ rel, path.Base(rel) } {
+            if ok, _ := path.Match(pattern, name); ok {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// selected returns true if the file at the relative path is to be processed
+func (opts *Options) selected(rel string) bool {
+    if matchAny(opts.Exclude, rel) {
+        return false
+    }
+    return len(opts.Include) == 0 || matchAny(opts.Include, rel)
+}
+
+// listFiles walks the input folder recursively and returns the log files to
+// process, in the order of the options. A folder matching an Exclude pattern
+// is skipped with its content. An archive is listed unless it is excluded,
+// the Include patterns being applied to its entries. A file or folder which
+// cannot be listed is passed to skip as a *FileError, and left out unless skip
+// returns an error.
+func listFiles(input string, opts *Options, skip func(err error) error) ([]*logFile, error) {
+    var files []*logFile
+    visit := func(p string, info os.FileInfo, err error) error {
+        if err != nil {
+            return err
+        }
+        rel, err := filepath.Rel(input, p)
+        if err != nil {
+            return err
+        }
+        if rel == "." {
+            return nil
+        }
+        rel = filepath.ToSlash(rel)
+        if info.IsDir() {
+            if matchAny(opts.Exclude, rel) {
+                return filepath.SkipDir
+            }
+            return nil
+        }
+        if !info.Mode().IsRegular() {
+            return nil
+        }
+        if isArchive(rel) {
+            // the patterns are applied to the entries of the archive
+            if matchAny(opts.Exclude, rel) {
+                return nil
+            }
+        } else if !opts.selected(rel) {
+            return nil
+        }
+        files = append(files, &logFile{ path : p, rel : rel, info : info })
+        return nil
+    }
+    err := filepath.Walk(input, func(p string, info os.FileInfo, err error) error {
+        if err = visit(p, info, err); err == nil || err == filepath.SkipDir {
+            return err
+        }
+        if err := skip(&FileError{ p, err }); err != nil {
+            return err
+        }
+        if info != nil && info.IsDir() {
+            return filepath.SkipDir
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    sort.Sort(logFiles{ files, opts.Order })
+    return files, nil
+}
+
+// logFiles is a list of logFile in a given order
+type logFiles struct {
+    files []*logFile
+    order FileOrder
+}
+
+func (lfs logFiles) Len() int {
+    return len(lfs.files)
+}
+
+func (lfs logFiles) Less(i, j int) bool {
+    if lfs.order == ByMtime {
+        if ti, tj := lfs.files[i].info.ModTime(), lfs.files[j].info.ModTime(); !ti.Equal(tj) {
+            return ti.Before(tj)
+        }
+    }
+    return lfs.files[i].rel < lfs.files[j].rel
+}
+
+func (lfs logFiles) Swap(i, j int) {
+    lfs.files[i], lfs.files[j] = lfs.files[j], lfs.files[i]
+}
+
+// The suffixes of the archive file names, with the compressed tar variants
+var tarSuffixes = []string{ ".tar", ".tar.gz", ".tgz", ".tar.bz2", ".tbz2", ".tar.xz", ".txz", ".tar.zst", ".tar.lz4" }
+
+// isArchive returns true if the file name is that of a tar or zip archive
+func isArchive(name string) bool {
+    return isTar(name) || strings.HasSuffix(strings.ToLower(name), ".zip")
+}
+
+// isTar returns true if the file name is that of a tar archive, possibly compressed
+func isTar(name string) bool {
+    name = strings.ToLower(name)
+    for _, suffix := range tarSuffixes {
+        if strings.HasSuffix(name, suffix) {
+            return true
+        }
+    }
+    return false
+}
+
+// walkArchive calls fn with each regular file of the archive selected by the
+// options, in the order of the archive, and stops at the first error of fn.
+// An entry is named as if the archive were a folder, e.g., logs.zip/2016/access.log.
+// The archive and its entries are reported as a *FileError if they cannot be read.
+func walkArchive(file *logFile, opts *Options, fn func(name string, r io.Reader) error) error {
+    if isTar(file.rel) {
+        return walkTar(file, opts, fn)
+    }
+    zr, err := zip.OpenReader(file.path)
+    if err != nil {
+        return &FileError{ file.path, err }
+    }
+    defer zr.Close()
+    for _, f := range zr.File {
+        if !f.Mode().IsRegular() {
+            continue
+        }
+        if !opts.selected(file.rel + "/" + f.Name) {
+            continue
+        }
+        name := file.path + "/" + f.Name
+        r, err := f.Open()
+        if err != nil {
+            err = fn(name, &errReader{ err })
+        } else {
+            err = fn(name, r)
+            r.Close()
+        }
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// walkTar calls fn with each regular file of the tar archive selected by the options.
+// The archive may be compressed.
+func walkTar(file *logFile, opts *Options, fn func(name string, r io.Reader) error) error {
+    fi, err := os.Open(file.path)
+    if err != nil {
+        return &FileError{ file.path, err }
+    }
+    defer fi.Close()
+    dr, _, err := decompress(fi)
+    if err != nil {
+        return &FileError{ file.path, err }
+    }
+    defer dr.Close()
+    tr := tar.NewReader(dr)
+    for {
+        hdr, err := tr.Next()
+        if err == io.EOF {
+            return nil
+        }
+        if err != nil {
+            return &FileError{ file.path, err }
+        }
+        if hdr.Typeflag != tar.TypeReg {
+            continue
+        }
+        if opts.selected(file.rel + "/" + hdr.Name) {
+            if err := fn(file.path + "/" + hdr.Name, tr); err != nil {
+                return err
+            }
+        }
+    }
+}
+
+// errReader is a reader failing with err, for an entry of an archive which
+// cannot be opened to be reported like an entry which cannot be read
+type errReader struct {
+    err error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+    return 0, r.err
+}
@@ -0,0 +1,210 @@
+package extract
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+)
+
+func init() {
+    f := NewJSONFormat(DefaultJSONFields)
+    f.Configure = configureJSON
+    RegisterFormat(string(JSON), f)
+}
+
+// JSONFields are the paths to the fields of a JSON log entry.
+// A path is a list of keys separated by dots, e.g., "request.query",
+// where a key is either the name of an object member or an array index.
+// An empty path means the field is absent from the log.
+type JSONFields struct {
+    // Query is the path to the SPARQL query
+    Query string
+    // Time is the path to the time of the request, either a RFC 3339 string
+    // or a number of seconds since the Unix epoch
+    Time string
+    // Client is the path to the address of the client
+    Client string
+    // Status is the path to the HTTP status code of the response
+    Status string
+    // Body is the path to the body of a POST request, which holds the query
+    // when there is none at the Query path
+    Body string
+    // ContentType is the path to the Content-Type header of a POST request
+    ContentType string
+}
+
+// DefaultJSONFields are the field paths of the json format when no option is given
+var DefaultJSONFields = JSONFields{
+    Query : "query",
+    Time : "time",
+    Client : "client",
+    Status : "status",
+    Body : "body",
+    ContentType : "content_type",
+}
+
+// NewJSONFormat returns a format reading JSON objects, one per line,
+// with the given field paths.
+func NewJSONFormat(fields JSONFields) *Format {
+    return &Format{
+        Split: jsonLines,
+        Entry: func(line []byte) (*Entry, error) {
+            return jsonEntry(line, fields)
+        },
+    }
+}
+
+// jsonEntry returns the query of the JSON log line with its metadata, the
+// line being decoded once. The query is at the Query path, or else in the
+// body of the POST request.
+func jsonEntry(line []byte, fields JSONFields) (*Entry, error) {
+    v, err := decodeJSON(line)
+    if err != nil {
+        return nil, err
+    }
+    var m Metadata
+    m.Time = jsonTime(jsonPath(v, fields.Time))
+    if client, ok := jsonPath(v, fields.Client).(string); ok {
+        m.Client = client
+    }
+    m.Status = jsonStatus(jsonPath(v, fields.Status))
+    r := jsonRequest(v, fields)
+    if query, ok := jsonPath(v, fields.Query).(string); ok {
+        if r != nil {
+            _, m.DefaultGraphs, m.NamedGraphs, _ = r.decode()
+        }
+        return &Entry{ Query : query, Metadata : m }, nil
+    }
+    if r != nil {
+        return r.entry(m)
+    }
+    return &Entry{ Metadata : m }, nil
+}
+
+// configureJSON returns a json format with the field paths given as a comma
+// separated list of name=path options, e.g., "query=params.query,time=@timestamp".
+// The fields not given keep their default path.
+func configureJSON(options string) (*Format, error) {
+    fields := DefaultJSONFields
+    for _, option := range strings.Split(options, ",") {
+        kv := strings.SplitN(option, "=", 2)
+        if len(kv) != 2 {
+            return nil, fmt.Errorf("Bad json option [%v], expected name=path", option)
+        }
+        switch strings.TrimSpace(kv[0]) {
+        case "query":
+            fields.Query = kv[1]
+        case "time":
+            fields.Time = kv[1]
+        case "client":
+            fields.Client = kv[1]
+        case "status":
+            fields.Status = kv[1]
+        case "body":
+            fields.Body = kv[1]
+        case "content_type":
+            fields.ContentType = kv[1]
+        default:
+            return nil, fmt.Errorf("Unknown json field [%v], expected one of query, time, client, status, body, content_type", kv[0])
+        }
+    }
+    if fields.Query == "" {
+        return nil, fmt.Errorf("The path to the query is required")
+    }
+    f := NewJSONFormat(fields)
+    f.Configure = configureJSON
+    return f, nil
+}
+
+// jsonRequest returns the POST request of the log entry, or nil if it has no body
+func jsonRequest(v interface{}, fields JSONFields) *sparqlRequest {
+    body, ok := jsonPath(v, fields.Body).(string)
+    if !ok || body == "" {
+        return nil
+    }
+    contentType, _ := jsonPath(v, fields.ContentType).(string)
+    return &sparqlRequest{ Method : "POST", ContentType : contentType, Body : []byte(body) }
+}
+
+// jsonLines reads the log file line by line and returns the non-empty lines.
+func jsonLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+    skipped := 0
+    for {
+        advance, token, err = bufio.ScanLines(data[skipped:], atEOF)
+        if err != nil || advance == 0 {
+            return skipped, nil, err
+        }
+        if len(bytes.TrimSpace(token)) != 0 {
+            return skipped + advance, token, nil
+        }
+        skipped += advance
+    }
+}
+
+// decodeJSON returns the decoded JSON value of the log line
+func decodeJSON(line []byte) (interface{}, error) {
+    var v interface{}
+    d := json.NewDecoder(bytes.NewReader(line))
+    d.UseNumber()
+    if err := d.Decode(&v); err != nil {
+        return nil, fmt.Errorf("%v in [%s]", err, line)
+    }
+    return v, nil
+}
+
+// jsonPath returns the value at the given path, or nil if there is none
+func jsonPath(v interface{}, path string) interface{} {
+    if path == "" {
+        return nil
+    }
+    for _, key := range strings.Split(path, ".") {
+        switch value := v.(type) {
+        case map[string]interface{}:
+            v = value[key]
+        case []interface{}:
+            i, err := strconv.Atoi(key)
+            if err != nil || i < 0 || i >= len(value) {
+                return nil
+            }
+            v = value[i]
+        default:
+            return nil
+        }
+    }
+    return v
+}
+
+// jsonTime returns the time of the JSON value, or the zero time
+func jsonTime(v interface{}) time.Time {
+    switch value := v.(type) {
+    case string:
+        if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+            return t
+        }
+        if t, err := time.Parse(clfTimeLayout, value); err == nil {
+            return t
+        }
+    case json.Number:
+        if secs, err := value.Float64(); err == nil {
+            return time.Unix(0, int64(secs * float64(time.Second)))
+        }
+    }
+    return time.Time{}
+}
+
+// jsonStatus returns the HTTP status of the JSON value, or 0
+func jsonStatus(v interface{}) int {
+    switch value := v.(type) {
+    case string:
+        status, _ := strconv.Atoi(value)
+        return status
+    case json.Number:
+        status, _ := strconv.Atoi(value.String())
+        return status
+    }
+    return 0
+}
@@ -0,0 +1,314 @@
+package extract
+
+import (
+    "bufio"
+    "compress/gzip"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// The namespaces of the RDF output
+const (
+    // Linked SPARQL Queries vocabulary http://lsq.aksw.org/
+    lsqv = "http://lsq.aksw.org/vocab#"
+    xsd = "http://www.w3.org/2001/XMLSchema#"
+    // the connected components, which have no LSQ term
+    slv = "https://github.com/scampi/sparql-log/vocab#"
+)
+
+// DefaultLSQBase is the namespace of the resources of the RDF output
+const DefaultLSQBase = "http://lsq.aksw.org/"
+
+// The prefixes of the Turtle output
+var lsqPrefixes = []struct{ prefix, ns string }{
+    { "lsqv", lsqv },
+    { "xsd", xsd },
+    { "slv", slv },
+}
+
+// lsqSink describes the log entries in RDF with the LSQ vocabulary. A query is
+// described once with its text and structural features:
+//
+//     <q-H> lsqv:text "..." ; lsqv:hash "H" ; lsqv:hasStructuralFeatures <q-H-sf> .
+//     <q-H-sf> lsqv:tpCount 3 ; lsqv:bgpCount 1 ; lsqv:joinVertexCount 1 ;
+//         lsqv:hasJoinVertex <q-H-jv1> ; slv:hasComponent <c-ID> .
+//     <q-H-jv1> lsqv:joinVertexType lsqv:Star ; lsqv:joinVertexDegree 2 .
+//     <c-ID> slv:complexity "1-1" .
+//
+// and each entry as a remote execution of the query, at the time of the entry,
+// by the client whose address is hashed:
+//
+//     <q-H> lsqv:hasRemoteExec <re-H-E> .
+//     <re-H-E> lsqv:atTime "..."^^xsd:dateTime ; lsqv:hostHash "..." .
+//
+// H is the hash of the query, ID the ComponentID, and E the hash of the
+// entry's log and line.
+type lsqSink struct {
+    path string
+    base string
+    file *os.File
+    gw *gzip.Writer
+    w *bufio.Writer
+    turtle bool
+    // the subject of the previous Turtle triple
+    subject *rdfTerm
+    // the first error writing the triples
+    err error
+    // the queries already described
+    queries map[componentID]bool
+}
+
+// openLSQSink creates the RDF file at path, in N-Triples if its extension is
+// .nt and in Turtle if it is .ttl, Gzip compressed if followed by .gz.
+func openLSQSink(path, base string) (*lsqSink, error) {
+    name := strings.TrimSuffix(path, ".gz")
+    var turtle bool
+    switch {
+    case strings.HasSuffix(name, ".nt"):
+    case strings.HasSuffix(name, ".ttl"):
+        turtle = true
+    default:
+        return nil, fmt.Errorf("Unknown RDF format of [%v], expected a .nt or .ttl extension", path)
+    }
+    if base == "" {
+        base = DefaultLSQBase
+    }
+    fo, err := os.OpenFile(path, os.O_WRONLY | os.O_TRUNC | os.O_CREATE, os.ModePerm)
+    if err != nil {
+        return nil, &OutputError{ path, err }
+    }
+    l := &lsqSink{ path : path, base : base, file : fo, turtle : turtle, queries : make(map[componentID]bool) }
+    var w io.Writer = fo
+    if name != path {
+        l.gw = gzip.NewWriter(fo)
+        w = l.gw
+    }
+    l.w = bufio.NewWriter(w)
+    if turtle {
+        for _, p := range lsqPrefixes {
+            fmt.Fprintf(l.w, "@prefix %v: <%v> .\n", p.prefix, p.ns)
+        }
+        l.w.WriteString("\n")
+    }
+    return l, nil
+}
+
+// add describes the log entry and its query
+func (l *lsqSink) add(p *parsed) error {
+    qid := getComponentID(p.entry.Query)
+    q := l.iri("q-" + qid.String())
+    if !l.queries[qid] {
+        l.queries[qid] = true
+        l.describe(q, qid.String(), p)
+    }
+    entry := getComponentID(p.log + ":" + strconv.Itoa(p.line)).String()
+    re := l.iri("re-" + qid.String() + "-" + entry[:16])
+    l.triple(q, iri(lsqv + "hasRemoteExec"), re)
+    if t := p.entry.Time; !t.IsZero() {
+        l.triple(re, iri(lsqv + "atTime"), typed(t.Format(time.RFC3339), xsd + "dateTime"))
+    }
+    if c := p.entry.Client; c != "" {
+        l.triple(re, iri(lsqv + "hostHash"), literal(getComponentID(c).String()))
+    }
+    if l.err != nil {
+        return &OutputError{ l.path, l.err }
+    }
+    return nil
+}
+
+// describe writes the text and structural features of the query q, whose hash is qh
+func (l *lsqSink) describe(q rdfTerm, qh string, p *parsed) {
+    sf := l.iri("q-" + qh + "-sf")
+    l.triple(q, iri(lsqv + "text"), literal(p.entry.Query))
+    l.triple(q, iri(lsqv + "hash"), literal(qh))
+    l.triple(q, iri(lsqv + "hasStructuralFeatures"), sf)
+
+    tps, joins := structuralFeatures(p)
+    l.triple(sf, iri(lsqv + "tpCount"), integer(tps))
+    l.triple(sf, iri(lsqv + "bgpCount"), integer(p.bgps))
+    l.triple(sf, iri(lsqv + "joinVertexCount"), integer(len(joins)))
+    for i := range joins {
+        l.triple(sf, iri(lsqv + "hasJoinVertex"), l.iri("q-" + qh + "-jv" + strconv.Itoa(i + 1)))
+    }
+    // the components as written in the other outputs
+    type component struct {
+        c rdfTerm
+        complexity string
+    }
+    var components []component
+    for _, cc := range p.components {
+        if len(cc.Complexity) != 1 || cc.Complexity[0] != 1 {
+            c := l.iri("c-" + ComponentID(componentQuery(cc)))
+            l.triple(sf, iri(slv + "hasComponent"), c)
+            components = append(components, component{ c, complexityName(cc.Complexity) })
+        }
+    }
+    for i, jv := range joins {
+        j := l.iri("q-" + qh + "-jv" + strconv.Itoa(i + 1))
+        l.triple(j, iri(lsqv + "joinVertexType"), iri(lsqv + jv.kind()))
+        l.triple(j, iri(lsqv + "joinVertexDegree"), integer(jv.in + jv.out))
+    }
+    for _, c := range components {
+        l.triple(c.c, iri(slv + "complexity"), literal(c.complexity))
+    }
+}
+
+// joinVertex is a subject or object of more than one triple pattern
+type joinVertex struct {
+    term string
+    // the number of triple patterns the vertex is the object and the subject of
+    in, out int
+}
+
+// kind returns the LSQ type of the join vertex
+func (jv *joinVertex) kind() string {
+    switch {
+    case jv.in == 0:
+        return "Star"
+    case jv.out == 0:
+        return "Sink"
+    case jv.in == 1 && jv.out == 1:
+        return "Path"
+    }
+    return "Hybrid"
+}
+
+// structuralFeatures returns the number of triple patterns of the parsed query,
+// and its join vertices sorted by term
+func structuralFeatures(p *parsed) (int, []*joinVertex) {
+    tps := 0
+    vertices := make(map[string]*joinVertex)
+    vertex := func(term string) *joinVertex {
+        v := vertices[term]
+        if v == nil {
+            v = &joinVertex{ term : term }
+            vertices[term] = v
+        }
+        return v
+    }
+    for _, tp := range p.triples {
+        tps++
+        vertex(tp.Subject.String()).out++
+        vertex(tp.Object.String()).in++
+    }
+    var joins []*joinVertex
+    for _, v := range vertices {
+        if v.in + v.out > 1 {
+            joins = append(joins, v)
+        }
+    }
+    sort.Slice(joins, func(i, j int) bool {
+        return joins[i].term < joins[j].term
+    })
+    return tps, joins
+}
+
+// triple writes the triple. In Turtle, the triples of a subject which follow
+// each other are grouped.
+func (l *lsqSink) triple(s, p, o rdfTerm) {
+    var err error
+    switch {
+    case !l.turtle:
+        _, err = fmt.Fprintf(l.w, "%v %v %v .\n", s, p, o)
+    case l.subject != nil && s == *l.subject:
+        _, err = fmt.Fprintf(l.w, " ;\n    %v %v", p.abbrev(), o.abbrev())
+    default:
+        if l.subject != nil {
+            l.w.WriteString(" .\n")
+        }
+        l.subject = &s
+        _, err = fmt.Fprintf(l.w, "%v %v %v", s, p.abbrev(), o.abbrev())
+    }
+    if err != nil && l.err == nil {
+        l.err = err
+    }
+}
+
+// iri returns the resource of the base namespace
+func (l *lsqSink) iri(local string) rdfTerm {
+    return iri(l.base + local)
+}
+
+// close flushes and closes the RDF file
+func (l *lsqSink) close() error {
+    if l.turtle && l.subject != nil {
+        l.w.WriteString(" .\n")
+    }
+    err := l.w.Flush()
+    if l.gw != nil {
+        if cerr := l.gw.Close(); err == nil {
+            err = cerr
+        }
+    }
+    if cerr := l.file.Close(); err == nil {
+        err = cerr
+    }
+    if err != nil {
+        return &OutputError{ l.path, err }
+    }
+    return nil
+}
+
+// rdfTerm is an IRI, or a literal with the datatype IRI if it is typed
+type rdfTerm struct {
+    isIRI bool
+    value string
+    datatype string
+}
+
+// iri returns the IRI
+func iri(s string) rdfTerm {
+    return rdfTerm{ isIRI : true, value : s }
+}
+
+// literal returns the string literal
+func literal(s string) rdfTerm {
+    return rdfTerm{ value : s }
+}
+
+// typed returns the literal of the datatype
+func typed(s, datatype string) rdfTerm {
+    return rdfTerm{ value : s, datatype : datatype }
+}
+
+// integer returns the xsd:integer literal
+func integer(n int) rdfTerm {
+    return typed(strconv.Itoa(n), xsd + "integer")
+}
+
+// String returns the term in N-Triples syntax
+func (t rdfTerm) String() string {
+    if t.isIRI {
+        return "<" + t.value + ">"
+    }
+    s := `"` + literalEscaper.Replace(t.value) + `"`
+    if t.datatype != "" {
+        s += "^^" + iri(t.datatype).String()
+    }
+    return s
+}
+
+// abbrev returns the term in Turtle syntax, the IRIs of the vocabularies
+// being prefixed and the integers written as numbers
+func (t rdfTerm) abbrev() string {
+    switch {
+    case t.isIRI:
+        for _, p := range lsqPrefixes {
+            if strings.HasPrefix(t.value, p.ns) {
+                return p.prefix + ":" + strings.TrimPrefix(t.value, p.ns)
+            }
+        }
+    case t.datatype == xsd + "integer":
+        return t.value
+    case t.datatype != "":
+        return `"` + literalEscaper.Replace(t.value) + `"^^` + iri(t.datatype).abbrev()
+    }
+    return t.String()
+}
+
+var literalEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
@@ -0,0 +1,141 @@
+package extract
+
+import (
+    "io/ioutil"
+    "net/url"
+    "os"
+    "path"
+    "regexp"
+    "strconv"
+    "strings"
+    "testing"
+)
+
+// The syntax of an N-Triples line with the terms written by the sink
+var ntriplesReg = regexp.MustCompile(`^<[^>]+> <[^>]+> (<[^>]+>|"(?:[^"\\]|\\.)*"(?:\^\^<[^>]+>)?) \.$`)
+
+func TestLSQ(t *testing.T) {
+    dir, err := ioutil.TempDir("", "extract")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    // the star query is executed twice
+    lines := strings.SplitAfter(tomcatLog, "\n")
+    log := lines[0] + lines[2] + lines[0]
+    q := "<http://example.org/q-" + ComponentID("select * { ?s a <C> ; <p> ?o } LIMIT 10") + ">"
+    for _, file := range []string{ "lsq.nt", "lsq.ttl" } {
+        opts := &Options{ LSQ : path.Join(dir, file), LSQBase : "http://example.org/" }
+        if _, err := ExtractReader(COMBINED, strings.NewReader(log), "test", path.Join(dir, "output"), opts); err != nil {
+            t.Fatal(err)
+        }
+        b, err := ioutil.ReadFile(opts.LSQ)
+        if err != nil {
+            t.Fatal(err)
+        }
+        rdf := string(b)
+        var expected []string
+        if file == "lsq.nt" {
+            for _, line := range strings.Split(strings.TrimSuffix(rdf, "\n"), "\n") {
+                if !ntriplesReg.MatchString(line) {
+                    t.Errorf("Bad N-Triples line %q", line)
+                }
+            }
+            expected = []string{
+                q + ` <http://lsq.aksw.org/vocab#text> "select * { ?s a <C> ; <p> ?o } LIMIT 10" .`,
+                `-sf> <http://lsq.aksw.org/vocab#tpCount> "2"^^<http://www.w3.org/2001/XMLSchema#integer> .`,
+                `-jv1> <http://lsq.aksw.org/vocab#joinVertexType> <http://lsq.aksw.org/vocab#Star> .`,
+                `-jv1> <http://lsq.aksw.org/vocab#joinVertexType> <http://lsq.aksw.org/vocab#Path> .`,
+            }
+        } else {
+            expected = []string{
+                "@prefix lsqv: <http://lsq.aksw.org/vocab#> .\n",
+                q + ` lsqv:text "select * { ?s a <C> ; <p> ?o } LIMIT 10" ;`,
+                "lsqv:tpCount 2 ;\n    lsqv:bgpCount 1 ;\n    lsqv:joinVertexCount 1 ;",
+                `lsqv:atTime "2015-10-10T13:55:36-07:00"^^xsd:dateTime ;`,
+            }
+        }
+        for _, s := range expected {
+            if !strings.Contains(rdf, s) {
+                t.Errorf("Expected %q in %v:\n%v", s, file, rdf)
+            }
+        }
+        if n := strings.Count(rdf, "hasRemoteExec"); n != 3 {
+            t.Errorf("Expected 3 executions in %v, but got %v", file, n)
+        }
+        if n := strings.Count(rdf, "#text>") + strings.Count(rdf, "lsqv:text"); n != 2 {
+            t.Errorf("Expected 2 queries described in %v, but got %v", file, n)
+        }
+    }
+}
+
+func TestLSQTypedLiteral(t *testing.T) {
+    dir, err := ioutil.TempDir("", "extract")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    // the text of the query holds quotes and a typed literal
+    query := `select * { ?s <p> "1"^^<http://www.w3.org/2001/XMLSchema#integer> ; <q> ?o }`
+    log := `127.0.0.1 - - [10/Oct/2015:13:55:36 -0700] "GET /sparql?query=` + url.QueryEscape(query) + ` HTTP/1.1" 200 2326` + "\n"
+    text := `"select * { ?s <p> \"1\"^^<http://www.w3.org/2001/XMLSchema#integer> ; <q> ?o }"`
+    q := "<http://example.org/q-" + ComponentID(query) + ">"
+    for _, file := range []string{ "lsq.nt", "lsq.ttl" } {
+        opts := &Options{ LSQ : path.Join(dir, file), LSQBase : "http://example.org/" }
+        if _, err := ExtractReader(COMBINED, strings.NewReader(log), "test", path.Join(dir, "output"), opts); err != nil {
+            t.Fatal(err)
+        }
+        b, err := ioutil.ReadFile(opts.LSQ)
+        if err != nil {
+            t.Fatal(err)
+        }
+        rdf := string(b)
+        expected := q + " lsqv:text " + text + " ;"
+        if file == "lsq.nt" {
+            for _, line := range strings.Split(strings.TrimSuffix(rdf, "\n"), "\n") {
+                if !ntriplesReg.MatchString(line) {
+                    t.Errorf("Bad N-Triples line %q", line)
+                }
+            }
+            expected = q + " <http://lsq.aksw.org/vocab#text> " + text + " ."
+        }
+        if !strings.Contains(rdf, expected) {
+            t.Errorf("Expected %q in %v:\n%v", expected, file, rdf)
+        }
+    }
+}
+
+func TestLSQVariablePredicate(t *testing.T) {
+    dir, err := ioutil.TempDir("", "extract")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    // the pattern of the variable predicate is in no component
+    query := `select * { ?s ?p ?o . ?o <r> ?z }`
+    log := `127.0.0.1 - - [10/Oct/2015:13:55:36 -0700] "GET /sparql?query=` + url.QueryEscape(query) + ` HTTP/1.1" 200 2326` + "\n"
+    opts := &Options{ LSQ : path.Join(dir, "lsq.nt"), LSQBase : "http://example.org/" }
+    if _, err := ExtractReader(COMBINED, strings.NewReader(log), "test", path.Join(dir, "output"), opts); err != nil {
+        t.Fatal(err)
+    }
+    b, err := ioutil.ReadFile(opts.LSQ)
+    if err != nil {
+        t.Fatal(err)
+    }
+    sf := "<http://example.org/q-" + ComponentID(query) + "-sf>"
+    integer := func(n int) string {
+        return ` "` + strconv.Itoa(n) + `"^^<http://www.w3.org/2001/XMLSchema#integer> .`
+    }
+    for _, expected := range []string{
+        sf + " <http://lsq.aksw.org/vocab#tpCount>" + integer(2),
+        sf + " <http://lsq.aksw.org/vocab#joinVertexCount>" + integer(1),
+        "<http://example.org/q-" + ComponentID(query) + "-jv1> <http://lsq.aksw.org/vocab#joinVertexType> <http://lsq.aksw.org/vocab#Path> .",
+    } {
+        if !strings.Contains(string(b), expected) {
+            t.Errorf("Expected %q in\n%s", expected, b)
+        }
+    }
+}
@@ -0,0 +1,163 @@
+package extract
+
+import (
+    "bytes"
+    "fmt"
+    "net/textproto"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+)
+
+func init() {
+    RegisterFormat(string(MODSECURITY), &Format{
+        Split: modsecurity,
+        Entry: modsecurityEntry,
+    })
+}
+
+// The ModSecurity audit log in the Serial format, where each transaction is
+// written as sections delimited by boundary lines:
+//   --a1b2c3d4-A--
+//   [05/Oct/2016:10:13:14 +0200] V9SrBn8AAQEAAB@ZAAAAAA 10.0.0.2 51234 10.0.0.1 80
+//   --a1b2c3d4-B--
+//   POST /sparql HTTP/1.1
+//   Host: dbpedia.org
+//   Content-Type: application/x-www-form-urlencoded
+//
+//   --a1b2c3d4-C--
+//   query=SELECT+...&default-graph-uri=http%3A%2F%2Fdbpedia.org
+//   --a1b2c3d4-F--
+//   HTTP/1.1 200 OK
+//
+//   --a1b2c3d4-Z--
+// The request body is in the section C, which requires SecRequestBodyAccess
+// and the audit log parts to include C.
+var modsecurityReg *regexp.Regexp = regexp.MustCompile(`^--[0-9a-zA-Z]+-([A-Z])--$`)
+
+// isModsecurityRecord returns true if the line starts a transaction
+func isModsecurityRecord(line []byte) bool {
+    m := modsecurityReg.FindSubmatch(line)
+    return m != nil && m[1][0] == 'A'
+}
+
+// modsecurityTransaction is a transaction of the audit log
+type modsecurityTransaction struct {
+    Metadata
+    request sparqlRequest
+}
+
+// Modsecurity reads the audit log transaction by transaction and returns the
+// ones which may hold a SPARQL query, in a query parameter or body. The
+// transaction is parsed once, by modsecurityEntry.
+func modsecurity(data []byte, atEOF bool) (advance int, token []byte, err error) {
+    skipped := 0
+    for {
+        advance, token, err = scanRecord(data[skipped:], atEOF, isModsecurityRecord)
+        if err != nil || advance == 0 {
+            return skipped, nil, err
+        }
+        skipped += advance
+        if token == nil {
+            continue
+        }
+        if bytes.Contains(token, []byte("query")) {
+            return skipped, token, nil
+        }
+    }
+}
+
+// ModsecurityEntry returns the query of the transaction, in the URI or in the
+// body, with information about the transaction.
+func modsecurityEntry(record []byte) (*Entry, error) {
+    e, err := parseModsecurity(record)
+    if err != nil {
+        return nil, err
+    }
+    return e.request.entry(e.Metadata)
+}
+
+// modsecuritySections returns the content of the sections of the transaction, by letter
+func modsecuritySections(record []byte) map[byte][]byte {
+    sections := make(map[byte][]byte)
+    var section byte
+    var start int
+    for end := 0; end < len(record); {
+        next := len(record)
+        if ind := bytes.IndexByte(record[end:], '\n'); ind != -1 {
+            next = end + ind + 1
+        }
+        if m := modsecurityReg.FindSubmatch(dropCR(bytes.TrimSuffix(record[end:next], []byte("\n")))); m != nil {
+            if section != 0 {
+                sections[section] = record[start:end]
+            }
+            section, start = m[1][0], next
+        }
+        end = next
+    }
+    if section != 0 {
+        sections[section] = record[start:]
+    }
+    return sections
+}
+
+// parseModsecurity parses the sections A, B, C and F of the transaction
+func parseModsecurity(record []byte) (*modsecurityTransaction, error) {
+    e := &modsecurityTransaction{}
+    sections := modsecuritySections(record)
+
+    // A: [time] unique_id client_ip client_port server_ip server_port
+    header := string(bytes.TrimSpace(sections['A']))
+    ind := strings.IndexByte(header, ']')
+    if !strings.HasPrefix(header, "[") || ind == -1 {
+        return nil, fmt.Errorf("Bad audit log header [%v]", header)
+    }
+    t, err := time.Parse(clfTimeLayout, header[1:ind])
+    if err != nil {
+        return nil, err
+    }
+    e.Time = t
+    if fields := strings.Fields(header[ind+1:]); len(fields) >= 2 {
+        e.RequestID, e.Client = fields[0], fields[1]
+    }
+
+    // B: request line and headers
+    lines := strings.Split(strings.TrimSpace(string(sections['B'])), "\n")
+    parts := strings.Fields(lines[0])
+    if len(parts) < 2 {
+        return nil, fmt.Errorf("Bad request line [%v]", lines[0])
+    }
+    e.Method, e.Path = parts[0], parts[1]
+    if len(parts) > 2 {
+        e.Protocol = parts[2]
+    }
+    if ind := strings.IndexByte(e.Path, '?'); ind != -1 {
+        e.Path, e.request.RawQuery = e.Path[:ind], e.Path[ind+1:]
+    }
+    for _, line := range lines[1:] {
+        kv := strings.SplitN(strings.TrimRight(line, "\r"), ":", 2)
+        if len(kv) != 2 {
+            continue
+        }
+        value := strings.TrimSpace(kv[1])
+        switch textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(kv[0])) {
+        case "Host":
+            e.Host = value
+        case "Content-Type":
+            e.request.ContentType = value
+        case "Referer":
+            e.Referer = value
+        case "User-Agent":
+            e.UserAgent = value
+        }
+    }
+    e.request.Method = e.Method
+    e.request.Body = bytes.TrimRight(sections['C'], "\r\n")
+
+    // F: response status line and headers
+    if status := strings.Fields(string(sections['F'])); len(status) >= 2 {
+        e.Status, _ = strconv.Atoi(status[1])
+    }
+    return e, nil
+}
@@ -0,0 +1,240 @@
+package extract
+
+import (
+    "bufio"
+    "encoding/gob"
+    "encoding/hex"
+    "fmt"
+    "io"
+    "os"
+    "strconv"
+    "strings"
+    "github.com/scampi/sparql-log/qparser"
+)
+
+// OutputFormat is the format of the output files
+type OutputFormat string
+
+const (
+    // TEXT writes the queries of the components separated by ### lines
+    TEXT OutputFormat = "text"
+    // JSONL writes a JSON object per component
+    JSONL OutputFormat = "jsonl"
+    // CSV writes a comma-separated record per component, after a header line
+    CSV OutputFormat = "csv"
+    // TSV writes a tab-separated record per component, after a header line.
+    // The backslashes, tabs, newlines and carriage returns of the values are
+    // escaped as \\, \t, \n and \r.
+    TSV OutputFormat = "tsv"
+)
+
+func (of OutputFormat) String() string {
+    return string(of)
+}
+
+// Set method needed for the flag package
+func (of *OutputFormat) Set(s string) error {
+    switch format := OutputFormat(strings.ToLower(s)); format {
+    case TEXT, JSONL, CSV, TSV:
+        *of = format
+        return nil
+    }
+    return fmt.Errorf("Unknown output format: [%v], expected %v, %v, %v or %v", s, TEXT, JSONL, CSV, TSV)
+}
+
+// extension returns the extension of the output files in that format
+func (of OutputFormat) extension() string {
+    if of == "" || of == TEXT {
+        return ".gz"
+    }
+    return "." + string(of) + ".gz"
+}
+
+// componentRecord is a connected component in the structured output formats,
+// with the query and the log entry it was first found in. Context is where the
+// component was nested in that query, e.g., optional/graph, or required.
+type componentRecord struct {
+    ID string `json:"id"`
+    Complexity []int `json:"complexity"`
+    Body string `json:"body"`
+    Context string `json:"context"`
+    Query string `json:"query"`
+    // Count is the number of occurrences in this run
+    Count int `json:"count"`
+    Log string `json:"log"`
+    Line int `json:"line"`
+    Client string `json:"client"`
+    Ident string `json:"ident"`
+    User string `json:"user"`
+    Time string `json:"time"`
+    Method string `json:"method"`
+    Path string `json:"path"`
+    Protocol string `json:"protocol"`
+    Status int `json:"status"`
+    Bytes int64 `json:"bytes"`
+    Referer string `json:"referer"`
+    UserAgent string `json:"user_agent"`
+    Host string `json:"host"`
+    RequestID string `json:"request_id"`
+    DefaultGraphs []string `json:"default_graphs"`
+    NamedGraphs []string `json:"named_graphs"`
+
+    stats *componentStats
+}
+
+// The columns of the CSV and TSV formats
+var recordColumns = []string{ "id", "complexity", "body", "context", "query", "count", "log", "line",
+    "client", "ident", "user", "time", "method", "path", "protocol", "status", "bytes",
+    "referer", "user_agent", "host", "request_id", "default_graphs", "named_graphs" }
+
+// newComponentRecord returns the record of a component first found in the parsed query
+func newComponentRecord(qid componentID, cc qparser.ConnectedComponent, p *parsed, stats *componentStats) *componentRecord {
+    m := p.entry.Metadata
+    return &componentRecord{
+        ID : qid.String(),
+        Complexity : cc.Complexity,
+        Body : cc.Body,
+        Context : contextName(cc.Context),
+        Query : p.entry.Query,
+        Log : p.log,
+        Line : p.line,
+        Client : m.Client,
+        Ident : m.Ident,
+        User : m.User,
+        Time : formatTime(m.Time),
+        Method : m.Method,
+        Path : m.Path,
+        Protocol : m.Protocol,
+        Status : m.Status,
+        Bytes : m.Bytes,
+        Referer : m.Referer,
+        UserAgent : m.UserAgent,
+        Host : m.Host,
+        RequestID : m.RequestID,
+        DefaultGraphs : m.DefaultGraphs,
+        NamedGraphs : m.NamedGraphs,
+        stats : stats,
+    }
+}
+
+// fields returns the values of the record in the order of recordColumns.
+// The complexity is written as in the names of the output files, e.g., 1-2,
+// and the graphs are separated by spaces.
+func (r *componentRecord) fields(qc string) []string {
+    return []string{ r.ID, qc, r.Body, r.Context, r.Query, strconv.Itoa(r.Count), r.Log, strconv.Itoa(r.Line),
+        r.Client, r.Ident, r.User, r.Time, r.Method, r.Path, r.Protocol, strconv.Itoa(r.Status),
+        strconv.FormatInt(r.Bytes, 10), r.Referer, r.UserAgent, r.Host, r.RequestID,
+        strings.Join(r.DefaultGraphs, " "), strings.Join(r.NamedGraphs, " ") }
+}
+
+// The escapes of the values of the TSV format
+var (
+    tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+    tsvUnescaper = strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n", `\r`, "\r")
+)
+
+// tsvLine returns the line of the values in the TSV format
+func tsvLine(values []string) string {
+    escaped := make([]string, len(values))
+    for i, v := range values {
+        escaped[i] = tsvEscaper.Replace(v)
+    }
+    return strings.Join(escaped, "\t") + "\n"
+}
+
+// outputFormat returns the format of the output files, TEXT by default
+func (opts *Options) outputFormat() OutputFormat {
+    if opts.OutputFormat == "" {
+        return TEXT
+    }
+    return opts.OutputFormat
+}
+
+// emit outputs a new component. In the TEXT format, the component is written
+// right away; in the structured formats, the record is spilled to a temporary
+// file, and written on close once the counts of occurrences are known.
+func (e *extractor) emit(qid componentID, qc string, cc qparser.ConnectedComponent, p *parsed) error {
+    key := e.partitionKey(qc, cc, p)
+    if e.opts.outputFormat() != TEXT {
+        return e.spill(key, newComponentRecord(qid, cc, p, e.stats[qid]))
+    }
+    of, err := e.outputFile(key)
+    if err != nil {
+        return err
+    }
+    if _, err := of.gw.Write([]byte("# id: " + qid.String() + "\n" + componentQuery(cc) + "###\n")); err != nil {
+        return &OutputError{ of.path, err }
+    }
+    return nil
+}
+
+// recordSpill is the temporary file of the records of the structured formats
+type recordSpill struct {
+    file *os.File
+    w *bufio.Writer
+    enc *gob.Encoder
+}
+
+// spilledRecord is a record with the partition key of its output file
+type spilledRecord struct {
+    Partition string
+    Record *componentRecord
+}
+
+// spill appends the record of the partition key to the temporary file,
+// which is created in the output folder with the first record
+func (e *extractor) spill(key string, r *componentRecord) error {
+    if e.records == nil {
+        fo, err := os.CreateTemp(e.output, ".records-")
+        if err != nil {
+            return &OutputError{ e.output, err }
+        }
+        e.records = &recordSpill{ file : fo, w : bufio.NewWriter(fo) }
+        e.records.enc = gob.NewEncoder(e.records.w)
+    }
+    if err := e.records.enc.Encode(spilledRecord{ key, r }); err != nil {
+        return &OutputError{ e.records.file.Name(), err }
+    }
+    return nil
+}
+
+// writeRecords writes the spilled records of the structured formats, in the
+// order the components were found, with their counts of occurrences. The
+// temporary file is removed.
+func (e *extractor) writeRecords() error {
+    if e.records == nil {
+        return nil
+    }
+    fo := e.records.file
+    defer os.Remove(fo.Name())
+    defer fo.Close()
+    if err := e.records.w.Flush(); err != nil {
+        return &OutputError{ fo.Name(), err }
+    }
+    if _, err := fo.Seek(0, io.SeekStart); err != nil {
+        return &OutputError{ fo.Name(), err }
+    }
+    dec := gob.NewDecoder(bufio.NewReader(fo))
+    for {
+        var sr spilledRecord
+        if err := dec.Decode(&sr); err == io.EOF {
+            return nil
+        } else if err != nil {
+            return &OutputError{ fo.Name(), err }
+        }
+        var qid componentID
+        if _, err := hex.Decode(qid[:], []byte(sr.Record.ID)); err != nil {
+            return &OutputError{ fo.Name(), err }
+        }
+        r := sr.Record
+        r.stats = e.stats[qid]
+        r.Count = r.stats.count
+        of, err := e.outputFile(sr.Partition)
+        if err != nil {
+            return err
+        }
+        if err := of.encode(r); err != nil {
+            return &OutputError{ of.path, err }
+        }
+    }
+}
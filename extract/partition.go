@@ -0,0 +1,246 @@
+package extract
+
+import (
+    "compress/gzip"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "path"
+    "regexp"
+    "strconv"
+    "strings"
+    "github.com/scampi/sparql-log/qparser"
+)
+
+// DefaultPartition is the key template of the output files, which writes a
+// file per complexity
+const DefaultPartition = "{complexity}"
+
+// DefaultMaxOpenFiles is the maximum number of output files open at once
+const DefaultMaxOpenFiles = 64
+
+// partitionValues are the placeholders of a key template, with their value
+// for a component and the log entry it was found in
+var partitionValues = map[string]func(qc string, cc qparser.ConnectedComponent, p *parsed) string{
+    "complexity" : func(qc string, cc qparser.ConnectedComponent, p *parsed) string {
+        return qc
+    },
+    "patterns" : func(qc string, cc qparser.ConnectedComponent, p *parsed) string {
+        patterns := 0
+        for _, n := range cc.Complexity {
+            patterns += n
+        }
+        return strconv.Itoa(patterns)
+    },
+    "file" : func(qc string, cc qparser.ConnectedComponent, p *parsed) string {
+        return path.Base(p.log)
+    },
+    "day" : func(qc string, cc qparser.ConnectedComponent, p *parsed) string {
+        if p.entry.Time.IsZero() {
+            return ""
+        }
+        return p.entry.Time.Format("2006-01-02")
+    },
+    "hour" : func(qc string, cc qparser.ConnectedComponent, p *parsed) string {
+        if p.entry.Time.IsZero() {
+            return ""
+        }
+        return p.entry.Time.Format("2006-01-02T15")
+    },
+    "host" : func(qc string, cc qparser.ConnectedComponent, p *parsed) string {
+        return p.entry.Host
+    },
+    "form" : func(qc string, cc qparser.ConnectedComponent, p *parsed) string {
+        return p.form
+    },
+}
+
+// The placeholders of a key template
+var placeholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// partition returns the key template of the options, DefaultPartition by default
+func (opts *Options) partition() string {
+    if opts.Partition == "" {
+        return DefaultPartition
+    }
+    return opts.Partition
+}
+
+// maxOpenFiles returns the maximum number of output files open at once
+func (opts *Options) maxOpenFiles() int {
+    if opts.MaxOpenFiles <= 0 {
+        return DefaultMaxOpenFiles
+    }
+    return opts.MaxOpenFiles
+}
+
+// checkPartition returns an error if the key template has an unknown
+// placeholder, or does not name a file within the output folder
+func checkPartition(template string) error {
+    for _, m := range placeholder.FindAllStringSubmatch(template, -1) {
+        if partitionValues[m[1]] == nil {
+            return fmt.Errorf("Unknown placeholder %v in the partition [%v]", m[0], template)
+        }
+    }
+    for _, elem := range strings.Split(template, "/") {
+        if elem == "" || elem == "." || elem == ".." {
+            return fmt.Errorf("Bad partition [%v], expected a relative path to a file", template)
+        }
+    }
+    return nil
+}
+
+// partitionKey returns the key of the output file of the component, the
+// placeholders of the template being replaced with the values of the component
+func (e *extractor) partitionKey(qc string, cc qparser.ConnectedComponent, p *parsed) string {
+    return placeholder.ReplaceAllStringFunc(e.opts.partition(), func(m string) string {
+        return partitionValue(partitionValues[m[1:len(m) - 1]](qc, cc, p))
+    })
+}
+
+// partitionValue returns the value as an element of a file name, its
+// characters other than letters, digits, dots, dashes and underscores
+// being replaced with underscores. An empty value is unknown.
+func partitionValue(v string) string {
+    if v == "" {
+        return "unknown"
+    }
+    v = strings.Map(func(r rune) rune {
+        switch {
+        case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '.', r == '-', r == '_':
+            return r
+        }
+        return '_'
+    }, v)
+    if strings.Trim(v, ".") == "" {
+        return strings.Repeat("_", len(v))
+    }
+    return v
+}
+
+// outputPath returns the path to the output file of the partition key. The
+// slashes of the key separate subfolders of the output folder.
+func (e *extractor) outputPath(key string) string {
+    dir, name := path.Split(key)
+    return path.Join(e.output, dir, "query_" + name + e.opts.outputFormat().extension())
+}
+
+// outputFile is the output file of a partition, open while it is written to
+type outputFile struct {
+    key string
+    path string
+    file *os.File
+    gw *gzip.Writer
+    // the writer of the CSV format
+    cw *csv.Writer
+    // encode writes a record in the structured formats
+    encode func(r *componentRecord) error
+}
+
+// outputFile returns the open output file of the partition key. The file is
+// created with the first component of the run in the partition; if it was
+// closed since, or if it is kept from an earlier run with a persistent dedup
+// index, a new Gzip member is appended to it. The least recently written file
+// is closed if MaxOpenFiles are open.
+func (e *extractor) outputFile(key string) (*outputFile, error) {
+    for i, of := range e.outputs {
+        if of.key == key {
+            // the most recently written file is last
+            copy(e.outputs[i:], e.outputs[i + 1:])
+            e.outputs[len(e.outputs) - 1] = of
+            return of, nil
+        }
+    }
+    if len(e.outputs) >= e.opts.maxOpenFiles() {
+        of := e.outputs[0]
+        e.outputs = e.outputs[1:]
+        if err := of.close(); err != nil {
+            return nil, err
+        }
+    }
+    of := &outputFile{ key : key, path : e.outputPath(key) }
+    if err := os.MkdirAll(path.Dir(of.path), os.ModePerm); err != nil {
+        return nil, &OutputError{ of.path, err }
+    }
+    fo, err := os.OpenFile(of.path, e.openFlag(e.partitions[key]), os.ModePerm)
+    if err != nil {
+        return nil, &OutputError{ of.path, err }
+    }
+    info, err := fo.Stat()
+    if err != nil {
+        fo.Close()
+        return nil, &OutputError{ of.path, err }
+    }
+    // the header starts the file
+    header := info.Size() == 0
+    of.file = fo
+    of.gw = gzip.NewWriter(fo)
+    switch e.opts.outputFormat() {
+    case JSONL:
+        enc := json.NewEncoder(of.gw)
+        enc.SetEscapeHTML(false)
+        of.encode = func(r *componentRecord) error {
+            return enc.Encode(r)
+        }
+    case TSV:
+        if header {
+            if _, err := io.WriteString(of.gw, tsvLine(recordColumns)); err != nil {
+                of.close()
+                return nil, &OutputError{ of.path, err }
+            }
+        }
+        of.encode = func(r *componentRecord) error {
+            _, err := io.WriteString(of.gw, tsvLine(r.fields(r.stats.complexity)))
+            return err
+        }
+    case CSV:
+        of.cw = csv.NewWriter(of.gw)
+        if header {
+            if err := of.cw.Write(recordColumns); err != nil {
+                of.close()
+                return nil, &OutputError{ of.path, err }
+            }
+        }
+        of.encode = func(r *componentRecord) error {
+            return of.cw.Write(r.fields(r.stats.complexity))
+        }
+    }
+    e.partitions[key] = true
+    e.outputs = append(e.outputs, of)
+    return of, nil
+}
+
+// openFlag returns the flag to open an output file with. A file is truncated
+// when first opened in the run, unless the components of earlier runs are in a
+// persistent dedup index: these are not written again, so the file is appended
+// to.
+func (e *extractor) openFlag(opened bool) int {
+    if opened || e.resumed {
+        return os.O_WRONLY | os.O_CREATE | os.O_APPEND
+    }
+    return os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+}
+
+// close flushes and closes the output file
+func (of *outputFile) close() error {
+    var err error
+    if of.cw != nil {
+        of.cw.Flush()
+        err = of.cw.Error()
+    }
+    if cerr := of.gw.Close(); err == nil {
+        err = cerr
+    }
+    if cerr := of.file.Sync(); err == nil {
+        err = cerr
+    }
+    if cerr := of.file.Close(); err == nil {
+        err = cerr
+    }
+    if err != nil {
+        return &OutputError{ of.path, err }
+    }
+    return nil
+}
@@ -0,0 +1,314 @@
+package extract
+
+import (
+    "bufio"
+    "bytes"
+    "io"
+    "runtime"
+    "sync"
+    "github.com/golang/glog"
+    "github.com/scampi/sparql-log/qparser"
+)
+
+// The number of log entries in a batch
+const batchSize = 256
+
+// logSource is a log to read. It calls fn with each stream of the log,
+// e.g., with each entry of an archive, in order, and stops at the first
+// error fn returns.
+type logSource func(fn func(name string, r io.Reader) error) error
+
+// batch is a sequence of entries of a log
+type batch struct {
+    name string
+    // the hash of the first entry of the log, which tells apart the logs of
+    // a name, e.g., stdin
+    fingerprint string
+    entries [][]byte
+    // the line of the log each entry starts on
+    lines []int
+    // end is true for the last batch of a log, err is set if the log could
+    // not be read entirely
+    end bool
+    err error
+    // results receives the parsed entries, in order
+    results chan []*parsed
+}
+
+// newBatch returns an empty batch of the log
+func newBatch(name string) *batch {
+    return &batch{ name : name, results : make(chan []*parsed, 1) }
+}
+
+// parsed is a log entry with the connected components of its query.
+// If the query could not be parsed, parseErr is the error of the parser,
+// and if the entry could not be decoded, err is an *EntryError.
+type parsed struct {
+    entry *Entry
+    log string
+    fingerprint string
+    line int
+    components qparser.ConnectedComponents
+    // the triple patterns of the query, for its RDF description
+    triples []qparser.PathPattern
+    // the number of basic graph patterns of the query
+    bgps int
+    // the form of the query, e.g., select
+    form string
+    parseErr error
+    err error
+}
+
+// workers returns the number of concurrent readers and parsers
+func (opts *Options) workers() int {
+    if opts.Workers > 0 {
+        return opts.Workers
+    }
+    return runtime.NumCPU()
+}
+
+// run extracts the connected components of the logs in a pipeline:
+// the logs are read concurrently and split into batches of entries, which a pool
+// of parsers turns into connected components. A single writer then consumes the
+// parsed batches in the order of the logs, so that the output is that of a
+// sequential run, as are the errors and the summary.
+func (e *extractor) run(sources []logSource) (*Summary, error) {
+    workers := e.opts.workers()
+    // closed when the writer stops at an error
+    done := make(chan struct{})
+
+    // parsers
+    jobs := make(chan *batch, workers)
+    var parsers sync.WaitGroup
+    for i := 0; i < workers; i++ {
+        parsers.Add(1)
+        go func() {
+            defer parsers.Done()
+            sg := &qparser.SparqlGraph{ Prefixes : e.opts.Prefixes }
+            for b := range jobs {
+                b.results <- e.parse(sg, b)
+            }
+        }()
+    }
+
+    // readers, each source having a queue of batches for the writer
+    type read struct {
+        source logSource
+        queue chan *batch
+    }
+    reads := make(chan read)
+    queues := make(chan chan *batch, workers)
+    go func() {
+        defer close(reads)
+        defer close(queues)
+        for _, source := range sources {
+            queue := make(chan *batch, workers)
+            select {
+            case queues <- queue:
+            case <-done:
+                return
+            }
+            select {
+            case reads <- read{ source, queue }:
+            case <-done:
+                return
+            }
+        }
+    }()
+    var readers sync.WaitGroup
+    for i := 0; i < workers; i++ {
+        readers.Add(1)
+        go func() {
+            defer readers.Done()
+            for r := range reads {
+                send := func(b *batch) error {
+                    select {
+                    case r.queue <- b:
+                    case <-done:
+                        return errAborted
+                    }
+                    select {
+                    case jobs <- b:
+                        return nil
+                    case <-done:
+                        return errAborted
+                    }
+                }
+                err := r.source(func(name string, log io.Reader) error {
+                    return e.scan(name, log, send)
+                })
+                if err != nil && err != errAborted {
+                    // the log could not be opened, or the archive walked
+                    b := newBatch("")
+                    b.end, b.err = true, err
+                    send(b)
+                }
+                close(r.queue)
+            }
+        }()
+    }
+    go func() {
+        readers.Wait()
+        close(jobs)
+    }()
+
+    // writer
+    err := e.collect(queues)
+    if err != nil {
+        // release the readers waiting for the writer
+        close(done)
+    }
+    parsers.Wait()
+    if cerr := e.close(); err == nil {
+        err = cerr
+    }
+    return &e.summary, err
+}
+
+// collect writes the parsed batches of the queues in order, until an error
+// stops the extraction
+func (e *extractor) collect(queues chan chan *batch) error {
+    for queue := range queues {
+        for b := range queue {
+            for _, p := range <-b.results {
+                if p.err != nil {
+                    // the entry is quarantined only if the extraction goes on
+                    if err := e.summary.skip(e.opts.OnError, p.err); err != nil {
+                        return err
+                    }
+                    ee := p.err.(*EntryError)
+                    if err := e.quarantine(ee.Log, ee.Line, ee.Err.Error(), string(ee.Data)); err != nil {
+                        return err
+                    }
+                    continue
+                }
+                e.summary.Queries++
+                if p.parseErr != nil {
+                    e.summary.Unparsed++
+                    if err := e.quarantine(p.log, p.line, parseErrorReason(p.parseErr), p.entry.Query); err != nil {
+                        return err
+                    }
+                    continue
+                }
+                if err := e.write(p); err != nil {
+                    return err
+                }
+            }
+            if b.err != nil {
+                if err := e.summary.skip(e.opts.OnError, b.err); err != nil {
+                    return err
+                }
+            } else if b.end {
+                e.summary.Logs++
+            }
+        }
+    }
+    return nil
+}
+
+// scan splits the log into entries, which are sent in batches.
+// The log may be compressed. It returns the error of send, which stops the scan.
+func (e *extractor) scan(name string, r io.Reader, send func(b *batch) error) error {
+    b := newBatch(name)
+    b.end = true
+    dr, codec, err := decompress(r)
+    if err != nil {
+        b.err = &FileError{ name, err }
+        return send(b)
+    }
+    defer dr.Close()
+    if codec != "" {
+        glog.V(1).Infof("[%v] is %v compressed", name, codec)
+    }
+
+    s := bufio.NewScanner(dr)
+    s.Buffer(make([]byte, 64 * 1024), maxEntrySize)
+    lc := &lineCounter{ split : e.format.split() }
+    s.Split(lc.Split)
+    b.end = false
+    for s.Scan() {
+        // the scanner reuses its buffer
+        data := append([]byte{}, s.Bytes()...)
+        if b.fingerprint == "" {
+            b.fingerprint = getComponentID(string(data)).String()
+        }
+        b.entries = append(b.entries, data)
+        b.lines = append(b.lines, lc.line)
+        if len(b.entries) == batchSize {
+            if err := send(b); err != nil {
+                return err
+            }
+            fingerprint := b.fingerprint
+            b = newBatch(name)
+            b.fingerprint = fingerprint
+        }
+    }
+    b.end = true
+    if s.Err() != nil {
+        b.err = &FileError{ name, s.Err() }
+    }
+    return send(b)
+}
+
+// parse returns the connected components of the queries in the batch
+func (e *extractor) parse(sg *qparser.SparqlGraph, b *batch) []*parsed {
+    var ps []*parsed
+    for i, data := range b.entries {
+        entry, err := newEntry(e.format, data)
+        if err != nil {
+            ps = append(ps, &parsed{ err : &EntryError{ b.name, b.lines[i], data, err } })
+            continue
+        }
+        if entry.Query == "" {
+            continue
+        }
+        qparser.Reset(sg, entry.Query)
+        p := &parsed{ entry : entry, log : b.name, fingerprint : b.fingerprint, line : b.lines[i] }
+        if err := sg.Parse(); err != nil {
+            glog.V(1).Infof("Failed to parse query\n%v\n%v", err, entry.Query)
+            p.parseErr = err
+        } else {
+            sg.Execute()
+            p.components = sg.ConnectedComponents()
+            p.bgps = sg.BGPCount()
+            p.form = sg.QueryForm()
+            if e.lsq != nil {
+                p.triples = sg.Query().Triples()
+            }
+        }
+        ps = append(ps, p)
+    }
+    return ps
+}
+
+// lineCounter wraps a split function to number the lines of the entries it returns
+type lineCounter struct {
+    split bufio.SplitFunc
+    // lines is the number of lines before the data given to split
+    lines int
+    // line is the line the last entry starts on, starting at 1
+    line int
+}
+
+func (lc *lineCounter) Split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+    advance, token, err = lc.split(data, atEOF)
+    if token != nil {
+        lc.line = lc.lines + 1 + bytes.Count(data[:entryOffset(data[:advance], token)], []byte("\n"))
+    }
+    lc.lines += bytes.Count(data[:advance], []byte("\n"))
+    return
+}
+
+// entryOffset returns the offset in data of the entry returned by a split function.
+// An entry which is not a slice of data, e.g., joining several records, is
+// located by its last line.
+func entryOffset(data, token []byte) int {
+    if off := cap(data) - cap(token); off >= 0 && off + len(token) <= len(data) && bytes.Equal(data[off:off + len(token)], token) {
+        return off
+    }
+    last := token[bytes.LastIndexByte(token, '\n') + 1:]
+    if off := bytes.LastIndex(data, last); off != -1 {
+        return off
+    }
+    return len(data)
+}
@@ -0,0 +1,71 @@
+package extract
+
+import (
+    "fmt"
+    "mime"
+    "net/url"
+    "strings"
+)
+
+// The parameters of the SPARQL 1.1 Protocol http://www.w3.org/TR/sparql11-protocol/
+const (
+    queryParam = "query"
+    defaultGraphParam = "default-graph-uri"
+    namedGraphParam = "named-graph-uri"
+)
+
+// sparqlRequest is a query operation of the SPARQL protocol
+type sparqlRequest struct {
+    // Method is the HTTP method of the request
+    Method string
+    // RawQuery is the encoded query string of the request URI
+    RawQuery string
+    // ContentType is the Content-Type header of a POST request
+    ContentType string
+    // Body is the body of a POST request
+    Body []byte
+}
+
+// decode returns the SPARQL query of the request, with the default and named
+// graphs of its dataset. The query is the query parameter of the request URI,
+// unless it is a POST request with the query in the body, either directly or
+// URL-encoded.
+func (r *sparqlRequest) decode() (query string, defaultGraphs, namedGraphs []string, err error) {
+    uri, err := url.ParseQuery(r.RawQuery)
+    if err != nil {
+        return "", nil, nil, fmt.Errorf("%s\n%v", r.RawQuery, err)
+    }
+    if !strings.EqualFold(r.Method, "POST") || len(r.Body) == 0 {
+        return uri.Get(queryParam), uri[defaultGraphParam], uri[namedGraphParam], nil
+    }
+
+    mediaType, _, err := mime.ParseMediaType(r.ContentType)
+    if err != nil && r.ContentType != "" {
+        return "", nil, nil, fmt.Errorf("Bad content type [%v]: %v", r.ContentType, err)
+    }
+    switch mediaType {
+    case "application/sparql-query":
+        // the dataset is given in the request URI
+        return string(r.Body), uri[defaultGraphParam], uri[namedGraphParam], nil
+    case "application/x-www-form-urlencoded", "":
+        form, err := url.ParseQuery(strings.TrimSpace(string(r.Body)))
+        if err != nil {
+            return "", nil, nil, fmt.Errorf("%s\n%v", r.Body, err)
+        }
+        return form.Get(queryParam), form[defaultGraphParam], form[namedGraphParam], nil
+    default:
+        // not a query operation, e.g., a SPARQL update
+        return "", nil, nil, nil
+    }
+}
+
+// entry returns the decoded query of the request, with the metadata of the log
+// completed by the dataset
+func (r *sparqlRequest) entry(m Metadata) (*Entry, error) {
+    query, defaultGraphs, namedGraphs, err := r.decode()
+    if err != nil {
+        return nil, err
+    }
+    m.DefaultGraphs, m.NamedGraphs = defaultGraphs, namedGraphs
+    return &Entry{ Query : query, Metadata : m }, nil
+}
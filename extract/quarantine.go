@@ -0,0 +1,58 @@
+package extract
+
+import (
+    "compress/gzip"
+    "fmt"
+    "os"
+    "path"
+    "strings"
+    "github.com/scampi/sparql-log/qparser"
+)
+
+// quarantinePath returns the path to the quarantine file
+func (e *extractor) quarantinePath() string {
+    if e.opts.Quarantine != "" {
+        return e.opts.Quarantine
+    }
+    return path.Join(e.output, "quarantine.gz")
+}
+
+// quarantine writes the text of a query which could not be decoded or parsed
+// into the quarantine file, after comment lines telling where the query was
+// found and why it failed:
+//
+//     # access.log:12
+//     # parse error at line 1 symbol 19 after iri
+//     select * { ?s <p> }
+//     ###
+//
+// As the output files, the quarantine is appended to with a persistent dedup
+// index.
+func (e *extractor) quarantine(log string, line int, reason, text string) error {
+    if e.quarantined == nil {
+        fo, err := os.OpenFile(e.quarantinePath(), e.openFlag(false), os.ModePerm)
+        if err != nil {
+            return &OutputError{ e.quarantinePath(), err }
+        }
+        e.files = append(e.files, fo)
+        e.quarantined = gzip.NewWriter(fo)
+    }
+    if !strings.HasSuffix(text, "\n") {
+        text += "\n"
+    }
+    record := fmt.Sprintf("# %v:%v\n# %v\n%v###\n", log, line, strings.Replace(reason, "\n", "\n# ", -1), text)
+    if _, err := e.quarantined.Write([]byte(record)); err != nil {
+        return &OutputError{ e.quarantinePath(), err }
+    }
+    e.summary.Quarantined++
+    return nil
+}
+
+// parseErrorReason describes where the parser failed
+func parseErrorReason(err error) string {
+    line, symbol, rule, ok := qparser.ParseErrorPosition(err)
+    if !ok {
+        return err.Error()
+    }
+    return fmt.Sprintf("parse error at line %v symbol %v after %v", line, symbol, rule)
+}
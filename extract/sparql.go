@@ -5,175 +5,407 @@ package extract
 
 import (
     "strconv"
+    "strings"
+    "crypto/sha256"
     "github.com/scampi/sparql-log/qparser"
-    "hash/fnv"
-    "hash"
-    "net/url"
-    "fmt"
-    "regexp"
-    "path"
+    "encoding/hex"
     "github.com/golang/glog"
-    "bufio"
-    "strings"
     "os"
-    "io/ioutil"
-    "compress/bzip2"
+    "io"
     "compress/gzip"
 )
 
-// The format of the log files
-type LogFormat uint
+// The maximum size of a log entry
+const maxEntrySize = 16 * 1024 * 1024
 
-const (
-    // Apache Combined Log Format http://httpd.apache.org/docs/current/logs.html
-    TOMCAT LogFormat = iota
-)
+// Stdin is the input name for reading the logs from the standard input
+const Stdin = "-"
 
-var logFormats = []string {
-    "TOMCAT",
+// Options configures the extraction.
+// A nil Options processes every file of the input folder by name.
+type Options struct {
+    // Include and Exclude are glob patterns, matched against the slash-separated
+    // path of a file relative to the input folder, and against its base name.
+    // A file is processed if it matches an Include pattern, or if there are
+    // none, and if it matches no Exclude pattern.
+    Include, Exclude []string
+    // Order is the order in which the files are processed, ByName by default
+    Order FileOrder
+    // Workers is the number of logs read concurrently, and the number of
+    // queries parsed concurrently. It is the number of CPUs if 0.
+    Workers int
+    // OnError is what to do with a log that cannot be read, or with an entry
+    // whose query cannot be decoded, Abort by default
+    OnError ErrorPolicy
+    // Quarantine is the path to the Gzip file receiving the queries which
+    // cannot be decoded or parsed, quarantine.gz in the output folder by default
+    Quarantine string
+    // Dedup is the path to the index of the components written by previous
+    // extractions, which are not written again. The index is created if it
+    // does not exist, and updated with the new components. The output files,
+    // the quarantine and the statistics of earlier runs are then appended to,
+    // unless the index is empty, e.g., once reset.
+    Dedup string
+    // OutputFormat is the format of the output files, TEXT by default
+    OutputFormat OutputFormat
+    // SQLite is the path to a SQLite database which also receives the log
+    // entries, their queries and connected components. The database is created
+    // if it does not exist, and appended to otherwise.
+    SQLite string
+    // LSQ is the path to an RDF file describing the log entries, their queries
+    // and structural features with the Linked SPARQL Queries vocabulary. It is
+    // written in N-Triples if the extension is .nt, in Turtle if it is .ttl,
+    // and compressed if followed by .gz.
+    LSQ string
+    // LSQBase is the namespace of the resources of the RDF file, DefaultLSQBase by default
+    LSQBase string
+    // Partition is the template of the key of the output file a component is
+    // written to, DefaultPartition by default. The file of the key K is query_K
+    // with the extension of the output format, the slashes of the key
+    // separating subfolders of the output folder. The placeholders of the
+    // template are replaced with values of the component, and of the log entry
+    // it is first found in:
+    //
+    //     {complexity}  the complexity of the component, e.g., 1-2
+    //     {patterns}    the number of triple patterns of the component
+    //     {file}        the base name of the log
+    //     {day}         the day of the entry, e.g., 2015-10-10
+    //     {hour}        the hour of the entry, e.g., 2015-10-10T13
+    //     {host}        the endpoint which received the query
+    //     {form}        the form of the query: select, construct, describe or ask
+    //
+    // A value which is not known, e.g., the host in a log without it, is unknown.
+    Partition string
+    // Prefixes holds the namespaces of the prefixes which the queries may use
+    // without declaring them, e.g., qparser.DBpediaPrefixes
+    Prefixes map[string]string
+    // MaxOpenFiles is the maximum number of output files open at once,
+    // DefaultMaxOpenFiles if 0. The least recently written file is closed
+    // to open another one, and appended to if written again.
+    MaxOpenFiles int
 }
 
-func (lf LogFormat) String() string {
-    return logFormats[lf]
+// Extract process the log files in input with the given format, and dumps the
+// connected components into output's files by the component's complexity, or
+// by the Partition of the options.
+// The input folder is walked recursively. Input log files may be compressed with
+// Gzip, Bzip2, XZ, Zstandard or LZ4, which is detected from the content of the
+// file. The entries of tar and zip archives are processed as if they were files
+// of the input folder. If input is Stdin, the log is read from the standard input.
+// Each component is written after a "# id: " comment line with its ComponentID,
+// or as a record of the OutputFormat of the options.
+// The occurrences of the components are written to the stats.tsv.gz sidecar.
+//
+// A log that cannot be read is reported as a *FileError, an entry whose query
+// cannot be decoded as an *EntryError, and a failure to write the output as an
+// *OutputError. The ErrorPolicy of the options decides whether the extraction
+// stops at such an error. The returned Summary reports on what was extracted
+// and skipped, up to the error if the extraction stopped.
+func Extract(logFormat LogFormat, input, output string, opts *Options) (*Summary, error) {
+    if opts == nil {
+        opts = &Options{}
+    }
+    if input == Stdin {
+        return ExtractReader(logFormat, os.Stdin, "stdin", output, opts)
+    }
+    e, err := newExtractor(logFormat, output, opts)
+    if err != nil {
+        return nil, err
+    }
+    files, err := listFiles(input, opts, func(err error) error {
+        return e.summary.skip(opts.OnError, err)
+    })
+    if err != nil {
+        e.close()
+        return &e.summary, err
+    }
+    var sources []logSource
+    for _, file := range files {
+        sources = append(sources, e.fileSource(file))
+    }
+    return e.run(sources)
 }
 
-// Set method needed for the flag package
-func (lf LogFormat) Set(s string) error {
-    s = strings.ToUpper(s)
-    switch s {
-    case "TOMCAT":
-        lf = TOMCAT
+// ExtractReader process the log read from r with the given format, and dumps
+// the connected components into output like Extract does. The name identifies
+// the log in errors. The log may be compressed like a file of the input folder.
+func ExtractReader(logFormat LogFormat, r io.Reader, name, output string, opts *Options) (*Summary, error) {
+    if opts == nil {
+        opts = &Options{}
+    }
+    e, err := newExtractor(logFormat, output, opts)
+    if err != nil {
+        return nil, err
     }
-    return fmt.Errorf("Unknown log format: [%v]", s)
+    return e.run([]logSource{ func(fn func(name string, r io.Reader) error) error {
+        return fn(name, r)
+    } })
 }
 
-// Extract process the log files in input with the given format, and dumps the
-// connected components into output's subfolders by the component's complexity.
-// Input log files may be Bzip2 or Gzip compressed.
-func Extract(logFormat LogFormat, input, output string) {
-    files, err := ioutil.ReadDir(input)
+// extractor holds the state of an extraction over several logs
+type extractor struct {
+    format *Format
+    opts *Options
+    output string
+    // the open output files, the most recently written last
+    outputs []*outputFile
+    // the partition keys of the output files created by the run
+    partitions map[string]bool
+    files []*os.File
+    uniq map[componentID]bool
+    // the components written by previous runs, replacing uniq if set
+    index *DedupIndex
+    // true if the index holds components of earlier runs, whose output files
+    // and statistics are appended to
+    resumed bool
+    // the quarantine file, opened with the first failed query
+    quarantined *gzip.Writer
+    // the occurrences of the components, by identifier
+    stats map[componentID]*componentStats
+    // the new components in the structured output formats, in order
+    records *recordSpill
+    // the database receiving the entries and components, if any
+    sink *sqliteSink
+    // the RDF description of the entries, if any
+    lsq *lsqSink
+    summary Summary
+}
+
+// newExtractor returns an extractor writing the components into output
+func newExtractor(logFormat LogFormat, output string, opts *Options) (*extractor, error) {
+    format, err := logFormat.format()
     if err != nil {
-        glog.Fatal(err)
+        return nil, err
+    }
+    if err := checkPartition(opts.partition()); err != nil {
+        return nil, err
+    }
+    for _, patterns := range [][]string{ opts.Include, opts.Exclude } {
+        if err := checkPatterns(patterns); err != nil {
+            return nil, err
+        }
     }
     err = os.MkdirAll(output, os.ModePerm)
     if err != nil {
-        glog.Fatal(err)
+        return nil, &OutputError{ output, err }
+    }
+    e := &extractor{
+        format : format,
+        opts : opts,
+        output : output,
+        partitions : make(map[string]bool),
+        uniq : make(map[componentID]bool),
+        stats : make(map[componentID]*componentStats),
     }
+    if opts.Dedup != "" {
+        if e.index, err = OpenDedupIndex(opts.Dedup); err != nil {
+            return nil, err
+        }
+        e.resumed = e.index.Len() != 0
+    }
+    if opts.SQLite != "" {
+        if e.sink, err = openSQLiteSink(opts.SQLite); err != nil {
+            e.close()
+            return nil, err
+        }
+    }
+    if opts.LSQ != "" {
+        if e.lsq, err = openLSQSink(opts.LSQ, opts.LSQBase); err != nil {
+            e.close()
+            return nil, err
+        }
+    }
+    return e, nil
+}
 
-    queries := make(map[string]*gzip.Writer)
-    sg := &qparser.SparqlGraph{}
-    h := fnv.New64a()
-    uniq := make(map[uint64]bool)
-    for _, file := range files {
-        glog.Infof("Processing [%v]", file.Name())
+// close writes the spilled records and the statistics, then flushes and closes the output files.
+// It returns the first error.
+func (e *extractor) close() error {
+    first := e.writeRecords()
+    if err := e.writeStats(); err != nil && first == nil {
+        first = err
+    }
+    for _, of := range e.outputs {
+        if err := of.close(); err != nil && first == nil {
+            first = err
+        }
+    }
+    if e.sink != nil {
+        if err := e.sink.close(); err != nil && first == nil {
+            first = err
+        }
+    }
+    if e.lsq != nil {
+        if err := e.lsq.close(); err != nil && first == nil {
+            first = err
+        }
+    }
+    if e.index != nil {
+        if err := e.index.Close(); err != nil && first == nil {
+            first = &OutputError{ e.index.Path(), err }
+        }
+    }
+    if e.quarantined != nil {
+        if err := e.quarantined.Close(); err != nil && first == nil {
+            first = &OutputError{ e.quarantinePath(), err }
+        }
+    }
+    for _, fo := range e.files {
+        if err := fo.Sync(); err != nil && first == nil {
+            first = &OutputError{ fo.Name(), err }
+        }
+        if err := fo.Close(); err != nil && first == nil {
+            first = &OutputError{ fo.Name(), err }
+        }
+    }
+    return first
+}
+
+// fileSource returns the source reading the log file, whose compression is
+// detected from its content. The entries of an archive are read as if they
+// were files of the input folder.
+func (e *extractor) fileSource(file *logFile) logSource {
+    return func(fn func(name string, r io.Reader) error) error {
+        glog.Infof("Processing [%v]", file.path)
+        if isArchive(file.rel) {
+            return walkArchive(file, e.opts, fn)
+        }
         // Read logs
-        fi, err := os.Open(path.Join(input, file.Name()))
+        fi, err := os.Open(file.path)
         if err != nil {
-            glog.Fatal(err)
+            return &FileError{ file.path, err }
         }
         defer fi.Close()
-        var s *bufio.Scanner
-        if strings.HasSuffix(fi.Name(), ".gz") {
-            r, err := gzip.NewReader(fi)
-            if err != nil {
-                glog.Fatal(err)
+        return fn(file.path, fi)
+    }
+}
+
+// write dumps the new connected components of the parsed query, and records
+// the entry with its components in the database and in RDF
+func (e *extractor) write(p *parsed) error {
+    var queryID int64
+    // the components of a query already in the database are recorded
+    record := false
+    if e.sink != nil {
+        var err error
+        if queryID, record, err = e.sink.addEntry(p); err != nil {
+            return err
+        }
+    }
+    if e.lsq != nil {
+        if err := e.lsq.add(p); err != nil {
+            return err
+        }
+    }
+    for _, cc := range p.components {
+        if len(cc.Complexity) != 1 || cc.Complexity[0] != 1 {
+            qid := getComponentID(componentQuery(cc))
+            qc := complexityName(cc.Complexity)
+            e.count(qid, qc, contextName(cc.Context), p)
+            if record {
+                if err := e.sink.addComponent(queryID, qid, qc, cc); err != nil {
+                    return err
+                }
             }
-            defer r.Close()
-            s = bufio.NewScanner(r)
-        } else if strings.HasSuffix(fi.Name(), ".bz2") {
-            s = bufio.NewScanner(bzip2.NewReader(fi))
-        } else {
-            s = bufio.NewScanner(fi)
-        }
-        switch logFormat {
-        case TOMCAT:
-            s.Split(tomcat)
-        default:
-            glog.Fatalf("Unknown format: [%v]", logFormat)
-        }
-
-        for s.Scan() {
-            qparser.Reset(sg, s.Text())
-            if err := sg.Parse(); err != nil {
-                glog.Warningf("Failed to parse query\n%v\n%v", err, s.Text())
+            isNew, err := e.seen(qid)
+            if err != nil {
+                return err
             }
-            sg.Execute()
-            for _, cc := range sg.ConnectedComponents() {
-                if len(cc.Complexity) != 1 || cc.Complexity[0] != 1 {
-                    query := "select * {\n" + cc.Body + "}\n"
-                    qid := getQueryId(h, query)
-                    if _, ok := uniq[qid]; !ok {
-                        glog.Infof("%v%v", s.Text(), cc)
-                        uniq[qid] = true
-                        qc := ""
-                        for i := range cc.Complexity {
-                            qc += strconv.Itoa(cc.Complexity[i])
-                            if i + 1 != len(cc.Complexity) {
-                                qc += "-"
-                            }
-                        }
-                        w := queries[qc]
-                        if w == nil {
-                            fo, err := os.OpenFile(path.Join(output, "query_" + qc + ".gz"), os.O_WRONLY | os.O_TRUNC | os.O_CREATE, os.ModePerm)
-                            if err != nil {
-                                glog.Fatal(err)
-                            }
-                            defer fo.Close()
-                            defer fo.Sync()
-                            w = gzip.NewWriter(fo)
-                            defer w.Close()
-                            queries[qc] = w
-                        }
-                        w.Write([]byte(query))
-                        w.Write([]byte("###\n"))
-                    }
+            if isNew {
+                glog.Infof("%v%v", p.entry.Query, cc)
+                if err := e.emit(qid, qc, cc, p); err != nil {
+                    return err
                 }
+                e.summary.Components++
             }
         }
-        if s.Err() != nil {
-            glog.Fatal(s.Err())
-        }
     }
+    return nil
 }
 
-var tomcatReg *regexp.Regexp = regexp.MustCompile("query=([^ ]+)")
+// componentQuery returns the query the component is written as
+func componentQuery(cc qparser.ConnectedComponent) string {
+    return "select * {\n" + cc.Body + "}\n"
+}
 
-// Tomcat reads the log file line by line and returns the decoded SPARQL query.
-func tomcat(data []byte, atEOF bool) (advance int, token []byte, err error) {
-    for {
-        advance, token, err = bufio.ScanLines(data, atEOF)
-        if err != nil {
-            glog.Fatal(err)
-        }
-        if advance == 0 {
-            return 0, nil, nil
-        }
-        m := tomcatReg.FindSubmatch(token)
-        if m == nil {
-            data = data[advance:]
-            continue
-        }
-        dec, err := url.QueryUnescape(string(m[1]))
-        if err != nil {
-            glog.Fatalf("%v\n%v", m[1], err)
+// complexityName returns the complexity as in the names of the output files, e.g., 1-2
+func complexityName(complexity []int) string {
+    qc := ""
+    for i := range complexity {
+        qc += strconv.Itoa(complexity[i])
+        if i + 1 != len(complexity) {
+            qc += "-"
         }
-        if ind := strings.LastIndex(dec, "}"); ind != -1 {
-            // discard anything after the where clause
-            dec = dec[:ind+1]
-        } else {
-            data = data[advance:]
-            continue
+    }
+    return qc
+}
+
+// contextName returns the operators the component is nested in, e.g., optional/graph,
+// or required if it is in the top-level group of the query
+func contextName(context []qparser.Operator) string {
+    if len(context) == 0 {
+        return "required"
+    }
+    names := make([]string, len(context))
+    for i, op := range context {
+        names[i] = string(op)
+    }
+    return strings.Join(names, "/")
+}
+
+// seen records the identifier of a component, and returns true if it is new
+func (e *extractor) seen(qid componentID) (bool, error) {
+    if e.index == nil {
+        if e.uniq[qid] {
+            return false, nil
         }
-        token = []byte(dec)
-        break
+        e.uniq[qid] = true
+        return true, nil
+    }
+    isNew, err := e.index.Add(qid[:])
+    if err != nil {
+        return false, &OutputError{ e.index.Path(), err }
+    }
+    return isNew, nil
+}
+
+// newEntry returns the SPARQL query of the log entry, with its metadata
+func newEntry(format *Format, data []byte) (*Entry, error) {
+    if format.Entry != nil {
+        return format.Entry(data)
+    }
+    query, err := format.Query(data)
+    if err != nil {
+        return nil, err
+    }
+    entry := &Entry{ Query : query }
+    if format.Metadata != nil {
+        entry.Metadata = format.Metadata(data)
     }
-    return
+    return entry, nil
 }
 
-// getQueryId returns the query identifier for given query
-func getQueryId(h hash.Hash64, query string) uint64 {
-    h.Reset()
-    h.Write([]byte(query))
-    return h.Sum64()
+// The size in bytes of the identifier of a component
+const componentIDSize = 16
+
+// componentID identifies a connected component: it is the first 128 bits of
+// the SHA-256 hash of the component's query
+type componentID [componentIDSize]byte
+
+func (id componentID) String() string {
+    return hex.EncodeToString(id[:])
 }
 
+// getComponentID returns the identifier of the component's query
+func getComponentID(query string) (id componentID) {
+    sum := sha256.Sum256([]byte(query))
+    copy(id[:], sum[:])
+    return id
+}
+
+// ComponentID returns the identifier of a connected component written as the
+// given query, e.g., "select * {\n    ?v0 <p> ?v1 .\n}\n". The identifier is
+// stable across runs, and is written in every output before the component.
+func ComponentID(query string) string {
+    return getComponentID(query).String()
+}
@@ -0,0 +1,669 @@
+package extract
+
+import (
+    "archive/tar"
+    "archive/zip"
+    "bytes"
+    "compress/gzip"
+    "database/sql"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "net/url"
+    "os"
+    "path"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+const tomcatLog = `127.0.0.1 - - [10/Oct/2015:13:55:36 -0700] "GET /sparql?query=select+*+%7B+%3Fs+a+%3CC%3E+%3B+%3Cp%3E+%3Fo+%7D+LIMIT+10 HTTP/1.1" 200 2326
+127.0.0.1 - - [10/Oct/2015:13:55:37 -0700] "GET /index.html HTTP/1.1" 200 10
+10.0.0.2 - - [10/Oct/2015:13:56:00 -0700] "GET /sparql?query=select+*+%7B+%3Fs+%3Cq%3E+%3Fo+.+%3Fo+%3Cr%3E+%3Fz+%7D HTTP/1.1" 200 12
+10.0.0.3 - - [10/Oct/2015:13:56:01 -0700] "GET /sparql?query=select+*+%7B+%3Fx+a+%3CC%3E+%3B+%3Cp%3E+%3Fy+%7D HTTP/1.1" 200 12
+`
+
+// readOutput returns the content of the gzipped output files, by file name,
+// without the statistics sidecar
+func readOutput(t *testing.T, output string) map[string]string {
+    content := readFiles(t, output)
+    delete(content, statsFile)
+    return content
+}
+
+// readFiles returns the content of the gzipped files of the folder, by file name
+func readFiles(t *testing.T, output string) map[string]string {
+    files, err := ioutil.ReadDir(output)
+    if err != nil {
+        t.Fatal(err)
+    }
+    content := make(map[string]string)
+    for _, file := range files {
+        fi, err := os.Open(path.Join(output, file.Name()))
+        if err != nil {
+            t.Fatal(err)
+        }
+        r, err := gzip.NewReader(fi)
+        if err != nil {
+            t.Fatal(err)
+        }
+        b, err := ioutil.ReadAll(r)
+        if err != nil {
+            t.Fatal(err)
+        }
+        fi.Close()
+        content[file.Name()] = string(b)
+    }
+    return content
+}
+
+func TestExtractReader(t *testing.T) {
+    output, err := ioutil.TempDir("", "extract")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(output)
+
+    summary, err := ExtractReader(TOMCAT, strings.NewReader(tomcatLog), "test", output, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if expected := (Summary{ Logs : 1, Queries : 3, Components : 2 }); !reflect.DeepEqual(*summary, expected) {
+        t.Errorf("Expected %+v, but got %+v", expected, *summary)
+    }
+    star := "select * {\n" +
+            "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <C> .\n" +
+            "    ?v0 <p> ?v1 .\n" +
+            "}\n"
+    chain := "select * {\n" +
+            "    ?v0 <q> ?v1 .\n" +
+            "    ?v1 <r> ?v2 .\n" +
+            "}\n"
+    expected := map[string]string{
+        "query_2.gz" : "# id: " + ComponentID(star) + "\n" + star + "###\n",
+        "query_1-1.gz" : "# id: " + ComponentID(chain) + "\n" + chain + "###\n",
+    }
+    actual := readOutput(t, output)
+    if len(actual) != len(expected) {
+        t.Errorf("Expected %v, but got %v", expected, actual)
+    }
+    for name, content := range expected {
+        if actual[name] != content {
+            t.Errorf("Expected %q in %v, but got %q", content, name, actual[name])
+        }
+    }
+}
+
+func TestListFiles(t *testing.T) {
+    input, err := ioutil.TempDir("", "input")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(input)
+    for _, name := range []string{
+        "2016/10/05/host1/access.log",
+        "2016/10/05/host1/access.log.md5",
+        "2016/10/05/host2/access.log.gz",
+        "2016/10/04/host1/access.log",
+        "2016/tmp/access.log",
+        "README",
+    } {
+        p := path.Join(input, name)
+        if err := os.MkdirAll(path.Dir(p), os.ModePerm); err != nil {
+            t.Fatal(err)
+        }
+        if err := ioutil.WriteFile(p, nil, os.ModePerm); err != nil {
+            t.Fatal(err)
+        }
+    }
+    opts := &Options{
+        Include : []string{ "access.log*" },
+        Exclude : []string{ "*.md5", "2016/tmp" },
+    }
+    files, err := listFiles(input, opts, func(err error) error {
+        return err
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    var actual []string
+    for _, file := range files {
+        actual = append(actual, file.rel)
+    }
+    expected := []string{
+        "2016/10/04/host1/access.log",
+        "2016/10/05/host1/access.log",
+        "2016/10/05/host2/access.log.gz",
+    }
+    if !reflect.DeepEqual(expected, actual) {
+        t.Errorf("Expected %v, but got %v", expected, actual)
+    }
+}
+
+func TestExtractArchives(t *testing.T) {
+    input, err := ioutil.TempDir("", "input")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(input)
+    output, err := ioutil.TempDir("", "output")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(output)
+
+    lines := strings.SplitAfter(tomcatLog, "\n")
+    // a tar.gz with a gzipped entry
+    fo, err := os.Create(path.Join(input, "logs.tar.gz"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    gw := gzip.NewWriter(fo)
+    tw := tar.NewWriter(gw)
+    var entry bytes.Buffer
+    compress(t, gzip.NewWriter(&entry), lines[0] + lines[1])
+    for _, f := range []struct{ name, content string }{
+        { "host1/access.log.gz", entry.String() },
+        { "host1/access.log.md5", `"GET /sparql?query=select+*+%7B+%3Fa+%3Cx%3E+%3Fb+.+%3Fb+%3Cy%3E+%3Fc+.+%3Fc+%3Cz%3E+%3Fd+%7D HTTP/1.1"` },
+    } {
+        if err := tw.WriteHeader(&tar.Header{ Name : f.name, Mode : 0644, Size : int64(len(f.content)), Typeflag : tar.TypeReg }); err != nil {
+            t.Fatal(err)
+        }
+        tw.Write([]byte(f.content))
+    }
+    compress(t, tw, "")
+    compress(t, gw, "")
+    fo.Close()
+    // a zip
+    fo, err = os.Create(path.Join(input, "logs.zip"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    zw := zip.NewWriter(fo)
+    w, err := zw.Create("host2/access.log")
+    if err != nil {
+        t.Fatal(err)
+    }
+    w.Write([]byte(lines[2] + lines[3]))
+    if err := zw.Close(); err != nil {
+        t.Fatal(err)
+    }
+    fo.Close()
+
+    if _, err := Extract(TOMCAT, input, output, &Options{ Exclude : []string{ "*.md5" } }); err != nil {
+        t.Fatal(err)
+    }
+    actual := readOutput(t, output)
+    if len(actual) != 2 || strings.Count(actual["query_2.gz"], "###") != 1 || strings.Count(actual["query_1-1.gz"], "###") != 1 {
+        t.Errorf("Unexpected output %v", actual)
+    }
+}
+
+func TestExtractWorkers(t *testing.T) {
+    input, err := ioutil.TempDir("", "input")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(input)
+
+    // enough entries for several batches per file, with queries repeated across files
+    for i := 0; i < 5; i++ {
+        var log bytes.Buffer
+        for j := 0; j < 2 * batchSize; j++ {
+            fmt.Fprintf(&log, `10.0.0.1 - - [10/Oct/2015:13:56:00 -0700] "GET /sparql?query=select+*+%%7B+%%3Fs+%%3Cp%d%%3E+%%3Fo+.+%%3Fo+%%3Cq%d%%3E+%%3Fz+%%7D HTTP/1.1" 200 12`+"\n", (i + j) % 700, j % 3)
+        }
+        if err := ioutil.WriteFile(path.Join(input, fmt.Sprintf("access%d.log", i)), log.Bytes(), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    // the raw content of the output files
+    extract := func(workers int) map[string][]byte {
+        output, err := ioutil.TempDir("", "output")
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer os.RemoveAll(output)
+        if _, err := Extract(TOMCAT, input, output, &Options{ Workers : workers }); err != nil {
+            t.Fatal(err)
+        }
+        files, err := ioutil.ReadDir(output)
+        if err != nil {
+            t.Fatal(err)
+        }
+        content := make(map[string][]byte)
+        for _, file := range files {
+            b, err := ioutil.ReadFile(path.Join(output, file.Name()))
+            if err != nil {
+                t.Fatal(err)
+            }
+            content[file.Name()] = b
+        }
+        return content
+    }
+    expected := extract(1)
+    if len(expected) == 0 {
+        t.Fatal("Expected some output")
+    }
+    for _, workers := range []int{ 2, 4, 8 } {
+        if actual := extract(workers); !reflect.DeepEqual(expected, actual) {
+            t.Errorf("The output with %d workers differs from a sequential run", workers)
+        }
+    }
+}
+
+func TestErrorPolicy(t *testing.T) {
+    input, err := ioutil.TempDir("", "input")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(input)
+    lines := strings.SplitAfter(tomcatLog, "\n")
+    for name, content := range map[string]string{
+        "1.log" : lines[0],
+        // a corrupted gzip header
+        "2.log.gz" : "\x1f\x8b\x08garbage",
+        // a query which cannot be unescaped
+        "3.log" : lines[1] + `10.0.0.2 - - [10/Oct/2015:13:56:00 -0700] "GET /sparql?query=select%zz HTTP/1.1" 200 12` + "\n" + lines[2],
+    } {
+        if err := ioutil.WriteFile(path.Join(input, name), []byte(content), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    for _, policy := range []ErrorPolicy{ Abort, Skip, Collect } {
+        output, err := ioutil.TempDir("", "output")
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer os.RemoveAll(output)
+        summary, err := Extract(TOMCAT, input, output, &Options{ OnError : policy, Workers : 2 })
+        switch policy {
+        case Abort:
+            if fe, ok := err.(*FileError); !ok || fe.Path != path.Join(input, "2.log.gz") {
+                t.Errorf("Expected a FileError on 2.log.gz, but got %v", err)
+            }
+            if summary.Logs != 1 || summary.Components != 1 {
+                t.Errorf("Expected to stop after 1.log, but got %+v", *summary)
+            }
+        default:
+            if err != nil {
+                t.Fatal(err)
+            }
+            if summary.Logs != 2 || summary.Queries != 2 || summary.SkippedLogs != 1 || summary.SkippedEntries != 1 {
+                t.Errorf("Unexpected summary %+v", *summary)
+            }
+            if policy == Skip && len(summary.Errors) != 0 {
+                t.Errorf("Expected no error to be kept, but got %v", summary.Errors)
+            }
+            if policy == Collect {
+                if len(summary.Errors) != 2 {
+                    t.Fatalf("Expected 2 errors, but got %v", summary.Errors)
+                }
+                if _, ok := summary.Errors[0].(*FileError); !ok {
+                    t.Errorf("Expected a FileError, but got %v", summary.Errors[0])
+                }
+                if ee, ok := summary.Errors[1].(*EntryError); !ok || ee.Line != 2 {
+                    t.Errorf("Expected an EntryError on line 2, but got %v", summary.Errors[1])
+                }
+            }
+        }
+    }
+}
+
+func TestMissingInput(t *testing.T) {
+    output, err := ioutil.TempDir("", "extract")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(output)
+    input := path.Join(output, "missing")
+    if _, err := Extract(TOMCAT, input, output, nil); err == nil {
+        t.Errorf("Expected a FileError, got %v", err)
+    } else if fe, ok := err.(*FileError); !ok || fe.Path != input {
+        t.Errorf("Expected a FileError on %v, got %v", input, err)
+    }
+    summary, err := Extract(TOMCAT, input, output, &Options{ OnError : Skip })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if summary.SkippedLogs != 1 || summary.Logs != 0 {
+        t.Errorf("Unexpected summary %+v", *summary)
+    }
+}
+
+func TestBadPattern(t *testing.T) {
+    output, err := ioutil.TempDir("", "extract")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(output)
+    input, err := ioutil.TempDir("", "input")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(input)
+    if err := ioutil.WriteFile(path.Join(input, "access.log"), []byte(tomcatLog), 0644); err != nil {
+        t.Fatal(err)
+    }
+    // a malformed pattern fails the extraction, whatever the error policy
+    for _, opts := range []*Options{ { Include : []string{ "access[" } }, { Exclude : []string{ "[" }, OnError : Skip } } {
+        _, err := Extract(TOMCAT, input, output, opts)
+        if err == nil || !strings.Contains(err.Error(), "Bad pattern") {
+            t.Errorf("Expected a bad pattern error, got %v", err)
+        } else if _, ok := err.(*FileError); ok {
+            t.Errorf("Expected a plain error, got the FileError %v", err)
+        }
+    }
+}
+
+func TestQuarantine(t *testing.T) {
+    output, err := ioutil.TempDir("", "extract")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(output)
+
+    lines := strings.SplitAfter(tomcatLog, "\n")
+    log := lines[0] + lines[1] +
+        `10.0.0.2 - - [10/Oct/2015:13:56:00 -0700] "GET /sparql?query=select%zz HTTP/1.1" 200 12` + "\n" +
+        `10.0.0.2 - - [10/Oct/2015:13:56:00 -0700] "GET /sparql?query=select+*+%7B+%3Fs+%3Cp%3E+%7D HTTP/1.1" 200 12` + "\n"
+    summary, err := ExtractReader(TOMCAT, strings.NewReader(log), "test", output, &Options{ OnError : Skip })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if summary.Queries != 2 || summary.Unparsed != 1 || summary.Quarantined != 2 || summary.Components != 1 {
+        t.Errorf("Unexpected summary %+v", *summary)
+    }
+    expected := "# test:3\n" +
+                "# select%zz\n" +
+                "# invalid URL escape \"%zz\"\n" +
+                `10.0.0.2 - - [10/Oct/2015:13:56:00 -0700] "GET /sparql?query=select%zz HTTP/1.1" 200 12` + "\n" +
+                "###\n" +
+                "# test:4\n" +
+                "# parse error at line 1 symbol 19 after iri\n" +
+                "select * { ?s <p> }\n" +
+                "###\n"
+    if actual := readOutput(t, output)["quarantine.gz"]; actual != expected {
+        t.Errorf("Expected %q, but got %q", expected, actual)
+    }
+
+    // the aborting entry is not quarantined
+    aborted, err := ioutil.TempDir("", "extract")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(aborted)
+    summary, err = ExtractReader(TOMCAT, strings.NewReader(log), "test", aborted, nil)
+    if _, ok := err.(*EntryError); !ok {
+        t.Fatalf("Expected an EntryError, got %v", err)
+    }
+    if summary.Quarantined != 0 {
+        t.Errorf("Unexpected summary %+v", *summary)
+    }
+    if actual, ok := readOutput(t, aborted)["quarantine.gz"]; ok {
+        t.Errorf("Expected no quarantine, but got %q", actual)
+    }
+}
+
+func TestComponentID(t *testing.T) {
+    id := ComponentID("select * {\n    ?v0 <p> ?v1 .\n}\n")
+    if expected := "71f3ba7b1d9fe2f5187c1b5322213daa"; id != expected {
+        t.Errorf("Expected %v, but got %v", expected, id)
+    }
+}
+
+func TestStats(t *testing.T) {
+    output, err := ioutil.TempDir("", "extract")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(output)
+
+    // the star occurs three times in two distinct queries
+    lines := strings.SplitAfter(tomcatLog, "\n")
+    log := lines[0] + lines[2] + lines[3] + strings.Replace(lines[3], "13:56:01", "14:00:00", 1)
+    if _, err := ExtractReader(COMBINED, strings.NewReader(log), "test", output, nil); err != nil {
+        t.Fatal(err)
+    }
+    star := "select * {\n" +
+            "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <C> .\n" +
+            "    ?v0 <p> ?v1 .\n" +
+            "}\n"
+    chain := "select * {\n" +
+             "    ?v0 <q> ?v1 .\n" +
+             "    ?v1 <r> ?v2 .\n" +
+             "}\n"
+    expected := "id\tcomplexity\tcount\tqueries\tfirst_seen\tlast_seen\tfiles\tcontexts\n" +
+                ComponentID(star) + "\t2\t3\t2\t2015-10-10T13:55:36-07:00\t2015-10-10T14:00:00-07:00\ttest\trequired\n" +
+                ComponentID(chain) + "\t1-1\t1\t1\t2015-10-10T13:56:00-07:00\t2015-10-10T13:56:00-07:00\ttest\trequired\n"
+    if actual := readFiles(t, output)[statsFile]; actual != expected {
+        t.Errorf("Expected %q, but got %q", expected, actual)
+    }
+}
+
+func TestStatsTomcat(t *testing.T) {
+    output, err := ioutil.TempDir("", "extract")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(output)
+
+    // the times of the tomcat lines are read as in the combined format
+    lines := strings.SplitAfter(tomcatLog, "\n")
+    if _, err := ExtractReader(TOMCAT, strings.NewReader(lines[0]), "test", output, &Options{ Partition : "{day}/{complexity}" }); err != nil {
+        t.Fatal(err)
+    }
+    if _, ok := readFiles(t, path.Join(output, "2015-10-10"))["query_2.gz"]; !ok {
+        t.Errorf("Expected the component in the partition of its day")
+    }
+    stats, err := readStats(path.Join(output, statsFile))
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, cs := range stats {
+        if formatTime(cs.first) != "2015-10-10T13:55:36-07:00" || !cs.last.Equal(cs.first) {
+            t.Errorf("Expected the time of the line, got %v and %v", cs.first, cs.last)
+        }
+    }
+    if len(stats) != 1 {
+        t.Errorf("Expected the statistics of the component, got %v", stats)
+    }
+}
+
+func TestStatsDedup(t *testing.T) {
+    output, err := ioutil.TempDir("", "extract")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(output)
+    index, err := ioutil.TempDir("", "index")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(index)
+
+    // the occurrences of the star in the first run are merged with the second
+    lines := strings.SplitAfter(tomcatLog, "\n")
+    opts := &Options{ Dedup : path.Join(index, "index") }
+    if _, err := ExtractReader(COMBINED, strings.NewReader(lines[0]), "day1", output, opts); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := ExtractReader(COMBINED, strings.NewReader(lines[2] + lines[3]), "day2", output, opts); err != nil {
+        t.Fatal(err)
+    }
+    star := "select * {\n" +
+            "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <C> .\n" +
+            "    ?v0 <p> ?v1 .\n" +
+            "}\n"
+    chain := "select * {\n" +
+             "    ?v0 <q> ?v1 .\n" +
+             "    ?v1 <r> ?v2 .\n" +
+             "}\n"
+    expected := "id\tcomplexity\tcount\tqueries\tfirst_seen\tlast_seen\tfiles\tcontexts\n" +
+                ComponentID(star) + "\t2\t2\t2\t2015-10-10T13:55:36-07:00\t2015-10-10T13:56:01-07:00\tday1,day2\trequired\n" +
+                ComponentID(chain) + "\t1-1\t1\t1\t2015-10-10T13:56:00-07:00\t2015-10-10T13:56:00-07:00\tday2\trequired\n"
+    if actual := readFiles(t, output)[statsFile]; actual != expected {
+        t.Errorf("Expected %q, but got %q", expected, actual)
+    }
+}
+
+func TestStatsFiles(t *testing.T) {
+    output, err := ioutil.TempDir("", "extract")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(output)
+    lines := strings.SplitAfter(tomcatLog, "\n")
+    for _, log := range []string{ "a,b", "c\td" } {
+        if _, err := ExtractReader(COMBINED, strings.NewReader(lines[0]), log, output, nil); err != nil {
+            t.Fatal(err)
+        }
+        stats := readFiles(t, output)[statsFile]
+        if expected := "\t" + fileEscaper.Replace(log) + "\trequired\n"; !strings.HasSuffix(stats, expected) {
+            t.Errorf("Expected the escaped log name %q, got %q", expected, stats)
+        }
+    }
+    if expected := `a\,b,c\td`; formatFiles([]string{ "a,b", "c\td" }) != expected {
+        t.Errorf("Expected %v, got %v", expected, formatFiles([]string{ "a,b", "c\td" }))
+    }
+    if files := []string{ "a,b", `c\`, "" }; !reflect.DeepEqual(files, splitFiles(formatFiles(files))) {
+        t.Errorf("Expected %q, got %q", files, splitFiles(formatFiles(files)))
+    }
+}
+
+func TestOutputFormats(t *testing.T) {
+    lines := strings.SplitAfter(tomcatLog, "\n")
+    log := lines[0] + lines[3]
+    star := "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <C> .\n" +
+            "    ?v0 <p> ?v1 .\n"
+    id := ComponentID("select * {\n" + star + "}\n")
+    for _, format := range []OutputFormat{ JSONL, CSV, TSV } {
+        output, err := ioutil.TempDir("", "extract")
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer os.RemoveAll(output)
+        if _, err := ExtractReader(COMBINED, strings.NewReader(log), "test", output, &Options{ OutputFormat : format }); err != nil {
+            t.Fatal(err)
+        }
+        content, ok := readOutput(t, output)["query_2." + string(format) + ".gz"]
+        if !ok {
+            t.Fatalf("No %v output in %v", format, readOutput(t, output))
+        }
+        var record map[string]interface{}
+        if format == JSONL {
+            if err := json.Unmarshal([]byte(content), &record); err != nil {
+                t.Fatal(err)
+            }
+            if !reflect.DeepEqual(record["complexity"], []interface{}{ 2.0 }) {
+                t.Errorf("Unexpected complexity %v", record["complexity"])
+            }
+        } else {
+            var rows [][]string
+            if format == TSV {
+                for _, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+                    fields := strings.Split(line, "\t")
+                    for i, f := range fields {
+                        fields[i] = tsvUnescaper.Replace(f)
+                    }
+                    rows = append(rows, fields)
+                }
+            } else {
+                rows, err = csv.NewReader(strings.NewReader(content)).ReadAll()
+                if err != nil {
+                    t.Fatal(err)
+                }
+            }
+            if len(rows) != 2 {
+                t.Fatalf("Expected a header and a record, got %v", rows)
+            }
+            record = make(map[string]interface{})
+            for i, column := range rows[0] {
+                record[column] = rows[1][i]
+            }
+            if record["complexity"] != "2" {
+                t.Errorf("Unexpected complexity %v", record["complexity"])
+            }
+        }
+        if record["id"] != id || record["body"] != star || record["context"] != "required" || fmt.Sprint(record["count"]) != "2" ||
+            record["client"] != "127.0.0.1" || record["time"] != "2015-10-10T13:55:36-07:00" || fmt.Sprint(record["line"]) != "1" {
+            t.Errorf("Unexpected %v record %v", format, record)
+        }
+    }
+}
+
+func TestComponentContexts(t *testing.T) {
+    dir, err := ioutil.TempDir("", "extract")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+    output := path.Join(dir, "output")
+    database := path.Join(dir, "queries.db")
+
+    // the star is required, and nested in an optional
+    query := "select * { ?s a <C> ; <p> ?o OPTIONAL { ?x a <C> ; <p> ?y } }"
+    log := `127.0.0.1 - - [10/Oct/2015:13:55:36 -0700] "GET /sparql?query=` + url.QueryEscape(query) + ` HTTP/1.1" 200 12` + "\n"
+    if _, err := ExtractReader(COMBINED, strings.NewReader(log), "test", output, &Options{ OutputFormat : JSONL, SQLite : database }); err != nil {
+        t.Fatal(err)
+    }
+    var record componentRecord
+    if err := json.Unmarshal([]byte(readOutput(t, output)["query_2.jsonl.gz"]), &record); err != nil {
+        t.Fatal(err)
+    }
+    if record.Context != "required" {
+        t.Errorf("Expected the context of the first occurrence, got %v", record.Context)
+    }
+    if stats := readFiles(t, output)[statsFile]; !strings.HasSuffix(stats, "\t2\t1\t2015-10-10T13:55:36-07:00\t2015-10-10T13:55:36-07:00\ttest\trequired,optional\n") {
+        t.Errorf("Expected the contexts of the star, got %q", stats)
+    }
+
+    db, err := sql.Open("sqlite", database)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer db.Close()
+    var contexts string
+    if err := db.QueryRow("SELECT group_concat(context, ',') FROM (SELECT context FROM query_components ORDER BY context)").Scan(&contexts); err != nil {
+        t.Fatal(err)
+    }
+    if contexts != "optional,required" {
+        t.Errorf("Expected the star in both contexts of the query, got %v", contexts)
+    }
+}
+
+func TestPartition(t *testing.T) {
+    output, err := ioutil.TempDir("", "extract")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(output)
+    // the complexity 2 is written before and after the 1-1 file is opened
+    log := tomcatLog + strings.Replace(strings.SplitAfter(tomcatLog, "\n")[0], "LIMIT", "OFFSET+1+LIMIT", 1)
+    log = strings.Replace(log, "%3Cp%3E+%3Fo+%7D+OFFSET", "%3Cq%3E+%3Fo+%7D+OFFSET", 1)
+    opts := &Options{ Partition : "{day}/{form}-{complexity}", MaxOpenFiles : 1, OutputFormat : CSV }
+    if _, err := ExtractReader(COMBINED, strings.NewReader(log), "test", output, opts); err != nil {
+        t.Fatal(err)
+    }
+    actual := readFiles(t, path.Join(output, "2015-10-10"))
+    if len(actual) != 2 {
+        t.Fatalf("Expected two partitions, got %v", actual)
+    }
+    for name, rows := range map[string]int{ "query_select-2.csv.gz" : 3, "query_select-1-1.csv.gz" : 2 } {
+        records, err := csv.NewReader(strings.NewReader(actual[name])).ReadAll()
+        if err != nil {
+            t.Fatal(err)
+        }
+        if len(records) != rows || records[0][0] != "id" {
+            t.Errorf("Expected a header and %v records in %v, got %v", rows - 1, name, records)
+        }
+    }
+
+    for _, partition := range []string{ "{unknown}", "../{complexity}", "{day}/" } {
+        if _, err := ExtractReader(COMBINED, strings.NewReader(log), "test", output, &Options{ Partition : partition }); err == nil {
+            t.Errorf("Expected an error with the partition %v", partition)
+        }
+    }
+}
@@ -0,0 +1,261 @@
+package extract
+
+import (
+    "database/sql"
+    "fmt"
+    "strings"
+    "github.com/scampi/sparql-log/qparser"
+    _ "modernc.org/sqlite"
+)
+
+// The tables of the SQLite database. A log entry has a query, which has
+// connected components, each with a complexity. The context of a component in
+// a query is where it is nested, e.g., optional/graph, or required; the same
+// component may occur in several contexts of a query.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS logs (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    fingerprint TEXT NOT NULL,
+    UNIQUE (name, fingerprint)
+);
+CREATE TABLE IF NOT EXISTS queries (
+    id INTEGER PRIMARY KEY,
+    hash TEXT NOT NULL UNIQUE,
+    text TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS entries (
+    id INTEGER PRIMARY KEY,
+    log_id INTEGER NOT NULL REFERENCES logs(id),
+    line INTEGER NOT NULL,
+    query_id INTEGER NOT NULL REFERENCES queries(id),
+    client TEXT,
+    ident TEXT,
+    user TEXT,
+    time TEXT,
+    method TEXT,
+    path TEXT,
+    protocol TEXT,
+    status INTEGER,
+    bytes INTEGER,
+    referer TEXT,
+    user_agent TEXT,
+    host TEXT,
+    request_id TEXT,
+    default_graphs TEXT,
+    named_graphs TEXT,
+    UNIQUE (log_id, line)
+);
+CREATE INDEX IF NOT EXISTS entries_query ON entries (query_id);
+CREATE TABLE IF NOT EXISTS complexities (
+    id INTEGER PRIMARY KEY,
+    vector TEXT NOT NULL UNIQUE,
+    stars INTEGER NOT NULL,
+    patterns INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS components (
+    id TEXT PRIMARY KEY,
+    body TEXT NOT NULL,
+    complexity_id INTEGER NOT NULL REFERENCES complexities(id)
+);
+CREATE TABLE IF NOT EXISTS query_components (
+    query_id INTEGER NOT NULL REFERENCES queries(id),
+    component_id TEXT NOT NULL REFERENCES components(id),
+    context TEXT NOT NULL,
+    PRIMARY KEY (query_id, component_id, context)
+);
+CREATE INDEX IF NOT EXISTS query_components_component ON query_components (component_id);
+`
+
+// The statements of the sink, prepared in the transaction of the extraction
+var sqliteStatements = []string{
+    insertLog : `INSERT OR IGNORE INTO logs (name, fingerprint) VALUES (?, ?)`,
+    selectLog : `SELECT id FROM logs WHERE name = ? AND fingerprint = ?`,
+    insertQuery : `INSERT OR IGNORE INTO queries (hash, text) VALUES (?, ?)`,
+    selectQuery : `SELECT id FROM queries WHERE hash = ?`,
+    insertEntry : `INSERT OR IGNORE INTO entries (log_id, line, query_id, client, ident, user, time, method, path,
+        protocol, status, bytes, referer, user_agent, host, request_id, default_graphs, named_graphs)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+    insertComplexity : `INSERT OR IGNORE INTO complexities (vector, stars, patterns) VALUES (?, ?, ?)`,
+    selectComplexity : `SELECT id FROM complexities WHERE vector = ?`,
+    insertComponent : `INSERT OR IGNORE INTO components (id, body, complexity_id) VALUES (?, ?, ?)`,
+    insertQueryComponent : `INSERT OR IGNORE INTO query_components (query_id, component_id, context) VALUES (?, ?, ?)`,
+    selectEntry : `SELECT query_id FROM entries WHERE log_id = ? AND line = ?`,
+}
+
+const (
+    insertLog = iota
+    selectLog
+    insertQuery
+    selectQuery
+    insertEntry
+    insertComplexity
+    selectComplexity
+    insertComponent
+    insertQueryComponent
+    selectEntry
+)
+
+// sqliteSink writes the log entries, their queries and connected components
+// into a SQLite database. The tables are created if they do not exist, and
+// the rows already in the database are kept, so that incremental runs append
+// to it. A log is identified by its name and the hash of its first entry, so
+// that a log read again, e.g., as it grew, is told apart from another log of
+// the same name, e.g., stdin or a rotated file. An entry is identified by its
+// log and line, and a query by its hash.
+type sqliteSink struct {
+    path string
+    db *sql.DB
+    tx *sql.Tx
+    stmts []*sql.Stmt
+    // the identifiers of the logs and complexities, by name and vector
+    logs, complexities map[string]int64
+}
+
+// openSQLiteSink opens the database at path, and starts the transaction of
+// the extraction
+func openSQLiteSink(path string) (*sqliteSink, error) {
+    db, err := sql.Open("sqlite", path)
+    if err != nil {
+        return nil, &OutputError{ path, err }
+    }
+    s := &sqliteSink{
+        path : path,
+        db : db,
+        logs : make(map[string]int64),
+        complexities : make(map[string]int64),
+    }
+    if err := s.init(); err != nil {
+        db.Close()
+        return nil, &OutputError{ path, err }
+    }
+    return s, nil
+}
+
+// init creates the tables and prepares the statements
+func (s *sqliteSink) init() error {
+    // the pragma applies to a connection
+    s.db.SetMaxOpenConns(1)
+    if _, err := s.db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+        return err
+    }
+    if _, err := s.db.Exec(sqliteSchema); err != nil {
+        return err
+    }
+    tx, err := s.db.Begin()
+    if err != nil {
+        return err
+    }
+    s.tx = tx
+    for _, query := range sqliteStatements {
+        stmt, err := tx.Prepare(query)
+        if err != nil {
+            return err
+        }
+        s.stmts = append(s.stmts, stmt)
+    }
+    return nil
+}
+
+// id returns the identifier of the row inserted, or already in the table
+// with the key, and whether it was inserted
+func (s *sqliteSink) id(insert, sel int, key []interface{}, args ...interface{}) (int64, bool, error) {
+    res, err := s.stmts[insert].Exec(args...)
+    if err != nil {
+        return 0, false, err
+    }
+    n, err := res.RowsAffected()
+    if err != nil {
+        return 0, false, err
+    }
+    var id int64
+    if err := s.stmts[sel].QueryRow(key...).Scan(&id); err != nil {
+        return 0, false, err
+    }
+    return id, n != 0, nil
+}
+
+// addEntry records the log entry and its query. It returns the identifier of
+// the query, and whether the query is new, its components not being recorded yet.
+func (s *sqliteSink) addEntry(p *parsed) (int64, bool, error) {
+    log := []interface{}{ p.log, p.fingerprint }
+    logID, ok := s.logs[p.log + "\x00" + p.fingerprint]
+    if !ok {
+        var err error
+        if logID, _, err = s.id(insertLog, selectLog, log, log...); err != nil {
+            return 0, false, &OutputError{ s.path, err }
+        }
+        s.logs[p.log + "\x00" + p.fingerprint] = logID
+    }
+    hash := getComponentID(p.entry.Query).String()
+    queryID, isNew, err := s.id(insertQuery, selectQuery, []interface{}{ hash }, hash, p.entry.Query)
+    if err != nil {
+        return 0, false, &OutputError{ s.path, err }
+    }
+    m := p.entry.Metadata
+    res, err := s.stmts[insertEntry].Exec(logID, p.line, queryID, nullString(m.Client), nullString(m.Ident),
+        nullString(m.User), nullString(formatTime(m.Time)), nullString(m.Method), nullString(m.Path),
+        nullString(m.Protocol), m.Status, m.Bytes, nullString(m.Referer), nullString(m.UserAgent),
+        nullString(m.Host), nullString(m.RequestID), nullString(strings.Join(m.DefaultGraphs, " ")),
+        nullString(strings.Join(m.NamedGraphs, " ")))
+    if err != nil {
+        return 0, false, &OutputError{ s.path, err }
+    }
+    if n, err := res.RowsAffected(); err != nil {
+        return 0, false, &OutputError{ s.path, err }
+    } else if n == 0 {
+        // the entry was recorded by a previous run, which read the same query
+        var stored int64
+        if err := s.stmts[selectEntry].QueryRow(logID, p.line).Scan(&stored); err != nil {
+            return 0, false, &OutputError{ s.path, err }
+        }
+        if stored != queryID {
+            return 0, false, &OutputError{ s.path, fmt.Errorf("Line %v of [%v] is recorded with another query", p.line, p.log) }
+        }
+    }
+    return queryID, isNew, nil
+}
+
+// addComponent records the connected component of the query
+func (s *sqliteSink) addComponent(queryID int64, qid componentID, qc string, cc qparser.ConnectedComponent) error {
+    complexityID, ok := s.complexities[qc]
+    if !ok {
+        patterns := 0
+        for _, n := range cc.Complexity {
+            patterns += n
+        }
+        var err error
+        complexityID, _, err = s.id(insertComplexity, selectComplexity, []interface{}{ qc }, qc, len(cc.Complexity), patterns)
+        if err != nil {
+            return &OutputError{ s.path, err }
+        }
+        s.complexities[qc] = complexityID
+    }
+    if _, err := s.stmts[insertComponent].Exec(qid.String(), cc.Body, complexityID); err != nil {
+        return &OutputError{ s.path, err }
+    }
+    if _, err := s.stmts[insertQueryComponent].Exec(queryID, qid.String(), contextName(cc.Context)); err != nil {
+        return &OutputError{ s.path, err }
+    }
+    return nil
+}
+
+// close commits the transaction and closes the database
+func (s *sqliteSink) close() error {
+    err := s.tx.Commit()
+    if cerr := s.db.Close(); err == nil {
+        err = cerr
+    }
+    if err != nil {
+        return &OutputError{ s.path, err }
+    }
+    return nil
+}
+
+// nullString returns nil for an empty string, stored as NULL
+func nullString(s string) interface{} {
+    if s == "" {
+        return nil
+    }
+    return s
+}
@@ -0,0 +1,95 @@
+package extract
+
+import (
+    "database/sql"
+    "io/ioutil"
+    "os"
+    "path"
+    "strings"
+    "testing"
+)
+
+func TestSQLite(t *testing.T) {
+    dir, err := ioutil.TempDir("", "extract")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+    database := path.Join(dir, "queries.db")
+
+    lines := strings.SplitAfter(tomcatLog, "\n")
+    for i, log := range []string{ lines[0] + lines[2], lines[0] + lines[2] + lines[3] } {
+        // the second run appends the log, whose entries are identified by line
+        if _, err := ExtractReader(COMBINED, strings.NewReader(log), "test", path.Join(dir, "output"), &Options{ SQLite : database }); err != nil {
+            t.Fatalf("Run %v: %v", i, err)
+        }
+    }
+
+    db, err := sql.Open("sqlite", database)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer db.Close()
+    for query, expected := range map[string]int{
+        "SELECT count(*) FROM logs" : 1,
+        "SELECT count(*) FROM entries" : 3,
+        "SELECT count(*) FROM queries" : 3,
+        "SELECT count(*) FROM components" : 2,
+        "SELECT count(*) FROM complexities" : 2,
+        "SELECT count(*) FROM query_components" : 3,
+        // the star component with the lines it came from
+        `SELECT count(*) FROM components c
+         JOIN complexities x ON x.id = c.complexity_id
+         JOIN query_components qc ON qc.component_id = c.id
+         JOIN entries e ON e.query_id = qc.query_id
+         WHERE x.vector = '2' AND e.client IN ('127.0.0.1', '10.0.0.3')` : 2,
+    } {
+        var actual int
+        if err := db.QueryRow(query).Scan(&actual); err != nil {
+            t.Fatalf("%v: %v", query, err)
+        }
+        if actual != expected {
+            t.Errorf("Expected %v for %v, but got %v", expected, query, actual)
+        }
+    }
+}
+
+func TestSQLiteSameLogName(t *testing.T) {
+    dir, err := ioutil.TempDir("", "extract")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+    database := path.Join(dir, "queries.db")
+
+    // two logs read from the standard input, whose lines are both recorded
+    lines := strings.SplitAfter(tomcatLog, "\n")
+    for i, log := range []string{ lines[0] + lines[2], lines[3] + lines[2] } {
+        if _, err := ExtractReader(COMBINED, strings.NewReader(log), "stdin", path.Join(dir, "output"), &Options{ SQLite : database }); err != nil {
+            t.Fatalf("Run %v: %v", i, err)
+        }
+    }
+    db, err := sql.Open("sqlite", database)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for query, expected := range map[string]int{
+        "SELECT count(*) FROM logs WHERE name = 'stdin'" : 2,
+        "SELECT count(*) FROM entries" : 4,
+    } {
+        var actual int
+        if err := db.QueryRow(query).Scan(&actual); err != nil {
+            t.Fatalf("%v: %v", query, err)
+        }
+        if actual != expected {
+            t.Errorf("Expected %v for %v, but got %v", expected, query, actual)
+        }
+    }
+    db.Close()
+
+    // a log starting as the first one, with another query on its second line
+    _, err = ExtractReader(COMBINED, strings.NewReader(lines[0] + lines[3]), "stdin", path.Join(dir, "output"), &Options{ SQLite : database })
+    if _, ok := err.(*OutputError); !ok {
+        t.Errorf("Expected an *OutputError for the conflicting line, but got %v", err)
+    }
+}
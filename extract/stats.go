@@ -0,0 +1,268 @@
+package extract
+
+import (
+    "bufio"
+    "compress/gzip"
+    "encoding/hex"
+    "fmt"
+    "os"
+    "path"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// The name of the statistics sidecar in the output folder
+const statsFile = "stats.tsv.gz"
+
+// componentStats are the occurrences of a component in the logs
+type componentStats struct {
+    id componentID
+    complexity string
+    // the number of occurrences
+    count int
+    // the identifiers of the distinct queries the component occurred in
+    queries map[componentID]bool
+    // the number of distinct queries in earlier runs
+    earlierQueries int
+    // when the component was first and last seen, if the log has timestamps
+    first, last time.Time
+    // the logs the component occurred in, in order
+    files []string
+    // the distinct contexts the component occurred in, in order, e.g., optional/graph
+    contexts []string
+}
+
+// count records an occurrence of the component in the parsed query
+func (e *extractor) count(qid componentID, qc, context string, p *parsed) {
+    cs := e.stats[qid]
+    if cs == nil {
+        cs = &componentStats{ id : qid, complexity : qc, queries : make(map[componentID]bool) }
+        e.stats[qid] = cs
+    }
+    cs.count++
+    cs.queries[getComponentID(p.entry.Query)] = true
+    if t := p.entry.Time; !t.IsZero() {
+        if cs.first.IsZero() || t.Before(cs.first) {
+            cs.first = t
+        }
+        if t.After(cs.last) {
+            cs.last = t
+        }
+    }
+    // the logs are processed in order, and a log is not seen again once another one starts
+    if len(cs.files) == 0 || cs.files[len(cs.files) - 1] != p.log {
+        cs.files = append(cs.files, p.log)
+    }
+    cs.contexts = appendNew(cs.contexts, context)
+}
+
+// appendNew appends the value to the list if it is not in it already
+func appendNew(list []string, v string) []string {
+    for _, w := range list {
+        if w == v {
+            return list
+        }
+    }
+    return append(list, v)
+}
+
+// writeStats writes the occurrences of the components seen in this run to
+// the statistics sidecar, a Gzip compressed tab-separated file with a header
+// line, where the most frequent components come first. With a dedup index
+// holding the components of earlier runs, the output files accumulate the
+// components of the runs, and the occurrences of earlier runs in the sidecar
+// are merged in; otherwise the sidecar is rewritten with the output files.
+//
+//     id	complexity	count	queries	first_seen	last_seen	files	contexts
+//
+// queries is the number of distinct queries the component occurred in, the
+// times are in RFC 3339 format, empty if the log has no timestamp, files are
+// the logs the component occurred in, separated by commas, and contexts are
+// the operators the component was nested in, e.g., optional/graph, or
+// required, separated by commas. The distinct queries of the runs are summed,
+// a query repeated in another run is counted again. The backslashes, commas,
+// tabs, newlines and carriage returns of the log names are escaped as \\, \,,
+// \t, \n and \r.
+func (e *extractor) writeStats() error {
+    if len(e.stats) == 0 {
+        return nil
+    }
+    name := path.Join(e.output, statsFile)
+    merged := e.stats
+    if e.resumed {
+        earlier, err := readStats(name)
+        if err != nil {
+            return &OutputError{ name, err }
+        }
+        merged = mergeStats(earlier, e.stats)
+    }
+    stats := make([]*componentStats, 0, len(merged))
+    for _, cs := range merged {
+        stats = append(stats, cs)
+    }
+    sort.Slice(stats, func(i, j int) bool {
+        if stats[i].count != stats[j].count {
+            return stats[i].count > stats[j].count
+        }
+        return stats[i].id.String() < stats[j].id.String()
+    })
+
+    fo, err := os.OpenFile(name, os.O_WRONLY | os.O_TRUNC | os.O_CREATE, os.ModePerm)
+    if err != nil {
+        return &OutputError{ name, err }
+    }
+    gw := gzip.NewWriter(fo)
+    w := bufio.NewWriter(gw)
+    fmt.Fprintln(w, "id\tcomplexity\tcount\tqueries\tfirst_seen\tlast_seen\tfiles\tcontexts")
+    for _, cs := range stats {
+        fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", cs.id, cs.complexity, cs.count, len(cs.queries) + cs.earlierQueries,
+            formatTime(cs.first), formatTime(cs.last), formatFiles(cs.files), strings.Join(cs.contexts, ","))
+    }
+    err = w.Flush()
+    if cerr := gw.Close(); err == nil {
+        err = cerr
+    }
+    if cerr := fo.Close(); err == nil {
+        err = cerr
+    }
+    if err != nil {
+        return &OutputError{ name, err }
+    }
+    return nil
+}
+
+// readStats returns the occurrences of the components in the statistics
+// sidecar, which are none if it does not exist. The sidecar of an earlier
+// version has no contexts column.
+func readStats(name string) (map[componentID]*componentStats, error) {
+    stats := make(map[componentID]*componentStats)
+    fi, err := os.Open(name)
+    if os.IsNotExist(err) {
+        return stats, nil
+    } else if err != nil {
+        return nil, err
+    }
+    defer fi.Close()
+    gr, err := gzip.NewReader(fi)
+    if err != nil {
+        return nil, err
+    }
+    scanner := bufio.NewScanner(gr)
+    scanner.Buffer(nil, 1 << 26)
+    // the header line
+    scanner.Scan()
+    for scanner.Scan() {
+        fields := strings.Split(scanner.Text(), "\t")
+        if len(fields) != 7 && len(fields) != 8 {
+            return nil, fmt.Errorf("Expected 8 columns, got %q", scanner.Text())
+        }
+        cs := &componentStats{ complexity : fields[1], queries : make(map[componentID]bool), files : splitFiles(fields[6]) }
+        if len(fields) == 8 && fields[7] != "" {
+            cs.contexts = strings.Split(fields[7], ",")
+        }
+        if _, err := hex.Decode(cs.id[:], []byte(fields[0])); err != nil {
+            return nil, err
+        }
+        if cs.count, err = strconv.Atoi(fields[2]); err != nil {
+            return nil, err
+        }
+        if cs.earlierQueries, err = strconv.Atoi(fields[3]); err != nil {
+            return nil, err
+        }
+        if cs.first, err = parseTime(fields[4]); err != nil {
+            return nil, err
+        }
+        if cs.last, err = parseTime(fields[5]); err != nil {
+            return nil, err
+        }
+        stats[cs.id] = cs
+    }
+    return stats, scanner.Err()
+}
+
+// mergeStats adds the occurrences of this run to the ones of earlier runs
+func mergeStats(earlier, run map[componentID]*componentStats) map[componentID]*componentStats {
+    for id, cs := range run {
+        ecs := earlier[id]
+        if ecs == nil {
+            earlier[id] = cs
+            continue
+        }
+        ecs.count += cs.count
+        ecs.queries = cs.queries
+        if ecs.first.IsZero() || !cs.first.IsZero() && cs.first.Before(ecs.first) {
+            ecs.first = cs.first
+        }
+        if cs.last.After(ecs.last) {
+            ecs.last = cs.last
+        }
+        for _, f := range cs.files {
+            ecs.files = appendNew(ecs.files, f)
+        }
+        for _, c := range cs.contexts {
+            ecs.contexts = appendNew(ecs.contexts, c)
+        }
+    }
+    return earlier
+}
+
+// fileEscaper escapes a log name of the files column
+var fileEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// formatFiles returns the escaped log names, separated by commas
+func formatFiles(files []string) string {
+    escaped := make([]string, len(files))
+    for i, f := range files {
+        escaped[i] = fileEscaper.Replace(f)
+    }
+    return strings.Join(escaped, ",")
+}
+
+// splitFiles returns the log names of the files column
+func splitFiles(s string) []string {
+    var files []string
+    var name []byte
+    for i := 0; i < len(s); i++ {
+        switch c := s[i]; {
+        case c == ',':
+            files = append(files, string(name))
+            name = name[:0]
+        case c == '\\' && i + 1 < len(s):
+            i++
+            switch s[i] {
+            case 't':
+                name = append(name, '\t')
+            case 'n':
+                name = append(name, '\n')
+            case 'r':
+                name = append(name, '\r')
+            default:
+                name = append(name, s[i])
+            }
+        default:
+            name = append(name, c)
+        }
+    }
+    if s != "" {
+        files = append(files, string(name))
+    }
+    return files
+}
+
+// parseTime returns the time in RFC 3339 format, or the zero time if it is empty
+func parseTime(s string) (time.Time, error) {
+    if s == "" {
+        return time.Time{}, nil
+    }
+    return time.Parse(time.RFC3339, s)
+}
+
+// formatTime returns the time in RFC 3339 format, or an empty string if it is zero
+func formatTime(t time.Time) string {
+    if t.IsZero() {
+        return ""
+    }
+    return t.Format(time.RFC3339)
+}
@@ -0,0 +1,58 @@
+package extract
+
+import (
+    "bufio"
+    "fmt"
+    "net/url"
+    "regexp"
+)
+
+func init() {
+    RegisterFormat(string(TOMCAT), &Format{
+        Split: tomcat,
+        Query: tomcatQuery,
+        Metadata: tomcatMetadata,
+    })
+}
+
+// The query parameter, whose value ends at the next parameter or at the end of the URI
+var tomcatReg *regexp.Regexp = regexp.MustCompile(`(?:^|[?&\s])query=([^&\s"]*)`)
+
+// Tomcat reads the log file line by line and returns the lines with a SPARQL query.
+func tomcat(data []byte, atEOF bool) (advance int, token []byte, err error) {
+    skipped := 0
+    for {
+        advance, token, err = bufio.ScanLines(data[skipped:], atEOF)
+        if err != nil || advance == 0 {
+            // consume the lines without a query and wait for more data
+            return skipped, nil, err
+        }
+        if tomcatReg.Match(token) {
+            return skipped + advance, token, nil
+        }
+        skipped += advance
+    }
+}
+
+// TomcatQuery returns the decoded SPARQL query of the log line.
+func tomcatQuery(line []byte) (string, error) {
+    m := tomcatReg.FindSubmatch(line)
+    if m == nil {
+        return "", fmt.Errorf("No query in [%s]", line)
+    }
+    dec, err := url.QueryUnescape(string(m[1]))
+    if err != nil {
+        return "", fmt.Errorf("%s\n%v", m[1], err)
+    }
+    return dec, nil
+}
+
+// tomcatMetadata returns the metadata of a log line in the Common or Combined
+// Log Format, or none if the line is in another format.
+func tomcatMetadata(line []byte) Metadata {
+    e, err := parseCLF(line)
+    if err != nil {
+        return Metadata{}
+    }
+    return e.Metadata
+}
@@ -0,0 +1,828 @@
+package qparser
+
+import (
+    "strconv"
+    "strings"
+)
+
+// Query is the abstract syntax tree of a SPARQL query, or of a subquery
+type Query struct {
+    // Base is the base IRI declared by the query, if any
+    Base string
+    // Prefixes holds the namespaces of the prefixes declared by the query
+    Prefixes map[string]string
+    // Form is the form of the query: select, construct, describe or ask
+    Form string
+    Distinct, Reduced bool
+    // Projection is the projection of a SELECT query, empty for SELECT *
+    Projection []Projection
+    // Template is the triple patterns of a CONSTRUCT query
+    Template []TriplePattern
+    // Describe is the resources of a DESCRIBE query, empty for DESCRIBE *
+    Describe []Term
+    // From and FromNamed are the IRIs of the default and named graphs
+    From, FromNamed []Term
+    // Where is the graph pattern of the query, nil if a DESCRIBE query has none
+    Where *GroupPattern
+    GroupBy []Projection
+    Having []Expression
+    OrderBy []OrderCondition
+    // Limit and Offset are -1 if the query has none
+    Limit, Offset int
+}
+
+// Projection is an expression, bound to Var with AS, or the zero Term if it is not
+type Projection struct {
+    Expression Expression
+    Var Term
+}
+
+// OrderCondition is an expression of the ORDER BY clause
+type OrderCondition struct {
+    Expression Expression
+    Descending bool
+}
+
+// Pattern is an element of a group graph pattern: a *BasicGraphPattern, a
+// *Filter, a *Bind, a *GroupPattern, a *Union, an *Optional, a *Minus, a
+// *Graph, a *Service, or a *Query for a subquery
+type Pattern interface {
+    pattern()
+}
+
+// GroupPattern is a group graph pattern, i.e., the elements between braces
+type GroupPattern struct {
+    Patterns []Pattern
+}
+
+// BasicGraphPattern is the triple patterns written between other elements
+// of a group. The blank node property lists and collections are written as
+// triple patterns of fresh blank nodes.
+type BasicGraphPattern struct {
+    Triples []PathPattern
+}
+
+// Filter is a FILTER of a group
+type Filter struct {
+    Expression Expression
+}
+
+// Bind is a BIND of a group
+type Bind struct {
+    Expression Expression
+    Var Term
+}
+
+// Union is the alternative group graph patterns joined with UNION
+type Union struct {
+    Branches []*GroupPattern
+}
+
+// Optional is an OPTIONAL group graph pattern
+type Optional struct {
+    Pattern *GroupPattern
+}
+
+// Minus is a MINUS group graph pattern
+type Minus struct {
+    Pattern *GroupPattern
+}
+
+// Graph is a GRAPH group graph pattern, whose graph is an IRI or a variable
+type Graph struct {
+    Name Term
+    Pattern *GroupPattern
+}
+
+// Service is a SERVICE group graph pattern, whose endpoint is an IRI or a variable
+type Service struct {
+    Silent bool
+    Endpoint Term
+    Pattern *GroupPattern
+}
+
+func (*GroupPattern) pattern() {}
+func (*BasicGraphPattern) pattern() {}
+func (*Filter) pattern() {}
+func (*Bind) pattern() {}
+func (*Union) pattern() {}
+func (*Optional) pattern() {}
+func (*Minus) pattern() {}
+func (*Graph) pattern() {}
+func (*Service) pattern() {}
+func (*Query) pattern() {}
+
+// PathPattern is a triple pattern whose predicate may be a property path
+type PathPattern struct {
+    Subject Term
+    // Path is a Term if the predicate is an IRI or a variable
+    Path Path
+    Object Term
+}
+
+// Triple returns the triple pattern, and false if its predicate is a property path
+func (pp PathPattern) Triple() (TriplePattern, bool) {
+    p, ok := pp.Path.(Term)
+    return TriplePattern{ pp.Subject, p, pp.Object }, ok
+}
+
+// Path is a property path: a Term, a *PathAlternative, a *PathSequence, a
+// *PathInverse, a *PathMod or a *PathNegated
+type Path interface {
+    path()
+}
+
+// PathAlternative is the paths separated with |
+type PathAlternative struct {
+    Paths []Path
+}
+
+// PathSequence is the paths separated with /
+type PathSequence struct {
+    Paths []Path
+}
+
+// PathInverse is the path preceded with ^
+type PathInverse struct {
+    Path Path
+}
+
+// PathMod is the path followed with *, ? or +
+type PathMod struct {
+    Path Path
+    Mod string
+}
+
+// PathNegated is the negated property set, e.g., !(<p>|^<q>), whose IRIs
+// are matched forward or inverse
+type PathNegated struct {
+    Forward, Inverse []Term
+}
+
+func (Term) path() {}
+func (*PathAlternative) path() {}
+func (*PathSequence) path() {}
+func (*PathInverse) path() {}
+func (*PathMod) path() {}
+func (*PathNegated) path() {}
+
+// Expression is an expression: a Term, a *BinaryExpression, a
+// *UnaryExpression, an *InExpression, a *FunctionCall, a *BuiltinCall, an
+// *Aggregate or an *Exists
+type Expression interface {
+    expression()
+}
+
+// BinaryExpression is the expressions joined with the operator, e.g., &&
+type BinaryExpression struct {
+    Op string
+    Left, Right Expression
+}
+
+// UnaryExpression is the expression preceded with the operator !, - or +
+type UnaryExpression struct {
+    Op string
+    Expression Expression
+}
+
+// InExpression is the expression compared to the list with IN, or NOT IN
+type InExpression struct {
+    Not bool
+    Expression Expression
+    List []Expression
+}
+
+// FunctionCall is a call to the function of the IRI
+type FunctionCall struct {
+    Function Term
+    Args []Expression
+}
+
+// BuiltinCall is a call to the builtin function of the name, in upper case, e.g., REGEX
+type BuiltinCall struct {
+    Name string
+    Args []Expression
+}
+
+// Aggregate is the aggregate of the name, in upper case, e.g., COUNT. The
+// expression is nil for COUNT(*).
+type Aggregate struct {
+    Name string
+    Distinct bool
+    Expression Expression
+    // Separator is the separator of GROUP_CONCAT, if any
+    Separator string
+}
+
+// Exists is an EXISTS, or NOT EXISTS, group graph pattern
+type Exists struct {
+    Not bool
+    Pattern *GroupPattern
+}
+
+func (Term) expression() {}
+func (*BinaryExpression) expression() {}
+func (*UnaryExpression) expression() {}
+func (*InExpression) expression() {}
+func (*FunctionCall) expression() {}
+func (*BuiltinCall) expression() {}
+func (*Aggregate) expression() {}
+func (*Exists) expression() {}
+
+// ParseQuery returns the abstract syntax tree of the SPARQL query
+func ParseQuery(query string) (*Query, error) {
+    sg := &SparqlGraph{}
+    Reset(sg, query)
+    if err := sg.Parse(); err != nil {
+        return nil, err
+    }
+    sg.Execute()
+    return sg.Query(), nil
+}
+
+// Query returns the abstract syntax tree of the parsed and executed query,
+// the prefixes which it does not declare being expanded with Prefixes
+func (p *SparqlGraph) Query() *Query {
+    b := &builder{ p, Newschema(), Newschema() }
+    root := p.tokenTree.AST()
+    if root == nil || root.pegRule != rulequeryContainer {
+        return nil
+    }
+    if prolog := find(root, ruleprolog); prolog != nil {
+        b.prolog(prolog)
+    }
+    q := b.query(find(find(root, rulequery), 0))
+    q.Base = b.decls.base
+    q.Prefixes = make(map[string]string)
+    for prefix, ns := range b.decls.prefixes {
+        q.Prefixes[prefix] = ns
+    }
+    return q
+}
+
+// Triples returns the triple patterns of the WHERE clause of the query, in
+// every group, subquery and EXISTS, in the order of the query
+func (q *Query) Triples() []PathPattern {
+    var triples []PathPattern
+    if q.Where != nil {
+        groupTriples(q.Where, &triples)
+    }
+    return triples
+}
+
+// groupTriples appends the triple patterns of the group to triples
+func groupTriples(g *GroupPattern, triples *[]PathPattern) {
+    for _, p := range g.Patterns {
+        switch p := p.(type) {
+        case *BasicGraphPattern:
+            *triples = append(*triples, p.Triples...)
+        case *GroupPattern:
+            groupTriples(p, triples)
+        case *Union:
+            for _, branch := range p.Branches {
+                groupTriples(branch, triples)
+            }
+        case *Optional:
+            groupTriples(p.Pattern, triples)
+        case *Minus:
+            groupTriples(p.Pattern, triples)
+        case *Graph:
+            groupTriples(p.Pattern, triples)
+        case *Service:
+            groupTriples(p.Pattern, triples)
+        case *Query:
+            *triples = append(*triples, p.Triples()...)
+        case *Filter:
+            expressionTriples(p.Expression, triples)
+        case *Bind:
+            expressionTriples(p.Expression, triples)
+        }
+    }
+}
+
+// expressionTriples appends the triple patterns of the EXISTS of the expression to triples
+func expressionTriples(e Expression, triples *[]PathPattern) {
+    var args []Expression
+    switch e := e.(type) {
+    case *Exists:
+        groupTriples(e.Pattern, triples)
+    case *BinaryExpression:
+        args = []Expression{ e.Left, e.Right }
+    case *UnaryExpression:
+        args = []Expression{ e.Expression }
+    case *InExpression:
+        args = append([]Expression{ e.Expression }, e.List...)
+    case *FunctionCall:
+        args = e.Args
+    case *BuiltinCall:
+        args = e.Args
+    }
+    for _, arg := range args {
+        expressionTriples(arg, triples)
+    }
+}
+
+// builder builds the abstract syntax tree from the parse tree of a query,
+// the terms being read as the semantic actions of the grammar do
+type builder struct {
+    p *SparqlGraph
+    // the blank nodes of the tree, renamed as in the graph of the query
+    blanks *schema
+    // the base and the prefixes declared by the query, as the prolog is read
+    decls *schema
+}
+
+// prolog declares the base and the prefixes of the prolog in order, an IRI
+// being resolved against the base declared before it
+func (b *builder) prolog(n *node32) {
+    for _, c := range children(n) {
+        iri := b.term(find(c, ruleiri))
+        switch c.pegRule {
+        case ruleprefixDecl:
+            prefix := ""
+            if pn := find(c, rulepnPrefix); pn != nil {
+                prefix = b.text(pn)
+            }
+            b.decls.addPrefix(prefix, iri)
+        case rulebaseDecl:
+            b.decls.base = iri.Value
+        }
+    }
+}
+
+// children returns the nodes under the node of the parse tree, the nodes
+// of the captured texts being replaced with theirs
+func children(n *node32) []*node32 {
+    var ar []*node32
+    for c := n.up; c != nil; c = c.next {
+        if c.pegRule == rulePegText {
+            ar = append(ar, children(c)...)
+        } else {
+            ar = append(ar, c)
+        }
+    }
+    return ar
+}
+
+// find returns the first node of the rule under n, or the first node if
+// the rule is 0, or nil if there is none
+func find(n *node32, rule pegRule) *node32 {
+    if n == nil {
+        return nil
+    }
+    for _, c := range children(n) {
+        if rule == 0 || c.pegRule == rule {
+            return c
+        }
+    }
+    return nil
+}
+
+// text returns the text of the node, without the whitespace and comments following it
+func (b *builder) text(n *node32) string {
+    end := n.end
+    last := n.up
+    for last != nil && last.next != nil {
+        last = last.next
+    }
+    if last != nil && last.pegRule == ruleskip {
+        end = last.begin
+    }
+    return string(b.p.buffer[n.begin:end])
+}
+
+// query returns the query of the selectQuery, constructQuery, describeQuery,
+// askQuery or subSelect node
+func (b *builder) query(n *node32) *Query {
+    q := &Query{ Limit : -1, Offset : -1 }
+    switch n.pegRule {
+    case ruleselectQuery, rulesubSelect:
+        q.Form = "select"
+    case ruleconstructQuery:
+        q.Form = "construct"
+    case ruledescribeQuery:
+        q.Form = "describe"
+    case ruleaskQuery:
+        q.Form = "ask"
+    }
+    for _, c := range children(n) {
+        switch c.pegRule {
+        case ruleselect:
+            b.projection(c, q)
+        case ruleconstruct:
+            if tb := find(c, ruletriplesBlock); tb != nil {
+                for _, pp := range b.triplesBlock(tb) {
+                    // SPARQL does not allow property paths in a template
+                    if tp, ok := pp.Triple(); ok {
+                        q.Template = append(q.Template, tp)
+                    }
+                }
+            }
+        case ruledescribe:
+            if t := find(c, rulevar); t != nil {
+                q.Describe = append(q.Describe, b.term(t))
+            } else if t := find(c, ruleiriref); t != nil {
+                q.Describe = append(q.Describe, b.term(t))
+            }
+        case ruledatasetClause:
+            iri := b.term(find(c, ruleiriref))
+            if find(c, ruleNAMED) != nil {
+                q.FromNamed = append(q.FromNamed, iri)
+            } else {
+                q.From = append(q.From, iri)
+            }
+        case rulewhereClause:
+            q.Where = b.group(find(c, rulegroupGraphPattern))
+        case rulesolutionModifier:
+            b.solutionModifier(c, q)
+        }
+    }
+    return q
+}
+
+// projection reads the select node into the query
+func (b *builder) projection(n *node32, q *Query) {
+    for _, c := range children(n) {
+        switch c.pegRule {
+        case ruleDISTINCT:
+            q.Distinct = true
+        case ruleREDUCED:
+            q.Reduced = true
+        case ruleprojectionElem:
+            if e := find(c, ruleexpression); e != nil {
+                q.Projection = append(q.Projection, Projection{ b.expression(e), b.term(find(c, rulevar)) })
+            } else {
+                q.Projection = append(q.Projection, Projection{ Expression : b.term(find(c, rulevar)) })
+            }
+        }
+    }
+}
+
+// solutionModifier reads the GROUP BY, HAVING, ORDER BY, LIMIT and OFFSET clauses into the query
+func (b *builder) solutionModifier(n *node32, q *Query) {
+    for _, c := range children(n) {
+        switch c.pegRule {
+        case rulegroupCondition:
+            if e := find(c, ruleexpression); e != nil {
+                pr := Projection{ Expression : b.expression(e) }
+                if v := find(c, rulevar); v != nil {
+                    pr.Var = b.term(v)
+                }
+                q.GroupBy = append(q.GroupBy, pr)
+            } else {
+                q.GroupBy = append(q.GroupBy, Projection{ Expression : b.expression(find(c, 0)) })
+            }
+        case ruleconstraint:
+            q.Having = append(q.Having, b.expression(c))
+        case ruleorderCondition:
+            oc := OrderCondition{}
+            for _, cc := range children(c) {
+                switch cc.pegRule {
+                case ruleDESC:
+                    oc.Descending = true
+                case ruleASC:
+                default:
+                    oc.Expression = b.expression(cc)
+                }
+            }
+            q.OrderBy = append(q.OrderBy, oc)
+        case rulelimitOffsetClauses:
+            for _, cc := range children(c) {
+                n, _ := strconv.Atoi(b.text(find(cc, ruleINTEGER)))
+                if cc.pegRule == rulelimit {
+                    q.Limit = n
+                } else {
+                    q.Offset = n
+                }
+            }
+        }
+    }
+}
+
+// group returns the group graph pattern of the groupGraphPattern or
+// optionalGraphPattern node
+func (b *builder) group(n *node32) *GroupPattern {
+    g := &GroupPattern{}
+    if s := find(n, rulesubSelect); s != nil {
+        g.Patterns = append(g.Patterns, b.query(s))
+    } else if gp := find(n, rulegraphPattern); gp != nil {
+        b.graphPattern(gp, g)
+    }
+    return g
+}
+
+// graphPattern appends the elements of the graphPattern node to the group
+func (b *builder) graphPattern(n *node32, g *GroupPattern) {
+    for _, c := range children(n) {
+        switch c.pegRule {
+        case rulebasicGraphPattern:
+            b.basicGraphPattern(c, g)
+        case rulegraphPatternNotTriples:
+            g.Patterns = append(g.Patterns, b.patternNotTriples(find(c, 0)))
+        case rulegraphPattern:
+            b.graphPattern(c, g)
+        }
+    }
+}
+
+// basicGraphPattern appends the triple patterns, filters and binds of the
+// basicGraphPattern node to the group
+func (b *builder) basicGraphPattern(n *node32, g *GroupPattern) {
+    for _, c := range children(n) {
+        switch c.pegRule {
+        case ruletriplesBlock:
+            g.Patterns = append(g.Patterns, &BasicGraphPattern{ b.triplesBlock(c) })
+        case rulefilterOrBind:
+            if e := find(c, ruleexpression); e != nil {
+                g.Patterns = append(g.Patterns, &Bind{ b.expression(e), b.term(find(c, rulevar)) })
+            } else {
+                g.Patterns = append(g.Patterns, &Filter{ b.expression(find(c, ruleconstraint)) })
+            }
+        }
+    }
+}
+
+// patternNotTriples returns the pattern of the child of a graphPatternNotTriples node
+func (b *builder) patternNotTriples(n *node32) Pattern {
+    switch n.pegRule {
+    case ruleoptionalGraphPattern:
+        return &Optional{ b.group(n) }
+    case rulegroupOrUnionGraphPattern:
+        u := &Union{}
+        for ; n != nil; n = find(n, rulegroupOrUnionGraphPattern) {
+            u.Branches = append(u.Branches, b.group(find(n, rulegroupGraphPattern)))
+        }
+        if len(u.Branches) == 1 {
+            return u.Branches[0]
+        }
+        return u
+    case ruleminusGraphPattern:
+        return &Minus{ b.group(find(n, rulegroupGraphPattern)) }
+    case rulegraphGraphPattern:
+        return &Graph{ b.term(find(n, 0).next), b.group(find(n, rulegroupGraphPattern)) }
+    case ruleserviceGraphPattern:
+        s := &Service{ Pattern : b.group(find(n, rulegroupGraphPattern)) }
+        for _, c := range children(n) {
+            switch c.pegRule {
+            case ruleSILENT:
+                s.Silent = true
+            case rulevar, ruleiriref:
+                s.Endpoint = b.term(c)
+            }
+        }
+        return s
+    }
+    return nil
+}
+
+// triplesBlock returns the triple patterns of the triplesBlock node
+func (b *builder) triplesBlock(n *node32) []PathPattern {
+    var triples []PathPattern
+    for _, c := range children(n) {
+        if c.pegRule != ruletriplesSameSubjectPath {
+            continue
+        }
+        cs := children(c)
+        s := b.node(cs[0], &triples)
+        if len(cs) > 1 {
+            b.propertyList(cs[1], s, &triples)
+        }
+    }
+    return triples
+}
+
+// propertyList appends the triple patterns of the subject s, whose
+// predicates and objects are in the propertyListPath node
+func (b *builder) propertyList(n *node32, s Term, triples *[]PathPattern) {
+    var verb Path
+    for _, c := range children(n) {
+        switch c.pegRule {
+        case rulevar:
+            verb = b.term(c)
+        case ruleverbPath:
+            verb = b.path(c)
+        case ruleobjectListPath:
+            for _, o := range children(c) {
+                if o.pegRule == ruleobjectPath {
+                    obj := b.node(find(o, 0), triples)
+                    *triples = append(*triples, PathPattern{ s, verb, obj })
+                }
+            }
+        case rulepropertyListPath:
+            b.propertyList(c, s, triples)
+        }
+    }
+}
+
+// node returns the term of the subject or object node, appending the triple
+// patterns of a blank node property list or a collection
+func (b *builder) node(n *node32, triples *[]PathPattern) Term {
+    switch n.pegRule {
+    case rulevarOrTerm, rulegraphTerm, rulegraphNodePath, ruletriplesNodePath:
+        return b.node(find(n, 0), triples)
+    case ruleblankNodePropertyListPath:
+        s := b.blanks.blankNode("")
+        b.propertyList(find(n, rulepropertyListPath), s, triples)
+        return s
+    case rulecollectionPath:
+        head, last := rdfNil, Term{}
+        for _, c := range children(n) {
+            if c.pegRule != rulegraphNodePath {
+                continue
+            }
+            item := b.node(c, triples)
+            bn := b.blanks.blankNode("")
+            if head == rdfNil {
+                head = bn
+            } else {
+                *triples = append(*triples, PathPattern{ last, rdfRest, bn })
+            }
+            *triples = append(*triples, PathPattern{ bn, rdfFirst, item })
+            last = bn
+        }
+        *triples = append(*triples, PathPattern{ last, rdfRest, rdfNil })
+        return head
+    }
+    return b.term(n)
+}
+
+// term returns the term of the node, as the semantic actions of the grammar read it
+func (b *builder) term(n *node32) Term {
+    switch n.pegRule {
+    case rulevar:
+        return variable(b.text(n))
+    case ruleiriref, ruleblankNode:
+        return b.term(find(n, 0))
+    case ruleiri:
+        return b.decls.resolve(b.text(n))
+    case ruleprefixedName:
+        return b.decls.expand(b.text(n), b.p.Prefixes)
+    case ruleliteral:
+        datatype := ""
+        if dt := find(n, ruleiriref); dt != nil {
+            datatype = b.term(dt).Value
+        }
+        return literal(b.text(n), datatype)
+    case rulenumericLiteral:
+        return numeric(b.text(n))
+    case rulebooleanLiteral:
+        if find(n, ruleTRUE) != nil {
+            return trueLiteral
+        }
+        return falseLiteral
+    case ruleblankNodeLabel:
+        return b.blanks.blankNode(b.text(n))
+    case ruleanon:
+        return b.blanks.blankNode("")
+    case rulenil:
+        return rdfNil
+    case ruleISA:
+        return rdfType
+    }
+    return Term{}
+}
+
+// path returns the property path of the node
+func (b *builder) path(n *node32) Path {
+    switch n.pegRule {
+    case ruleverbPath, rulepath:
+        return b.path(find(n, 0))
+    case rulepathAlternative, rulepathSequence:
+        var paths []Path
+        for _, c := range children(n) {
+            if c.pegRule == rulepathSequence || c.pegRule == rulepathElt {
+                paths = append(paths, b.path(c))
+            }
+        }
+        switch {
+        case len(paths) == 1:
+            return paths[0]
+        case n.pegRule == rulepathAlternative:
+            return &PathAlternative{ paths }
+        }
+        return &PathSequence{ paths }
+    case rulepathElt:
+        p := b.path(find(n, rulepathPrimary))
+        if mod := find(n, rulepathMod); mod != nil {
+            p = &PathMod{ p, b.text(mod) }
+        }
+        if find(n, ruleINVERSE) != nil {
+            p = &PathInverse{ p }
+        }
+        return p
+    case rulepathPrimary:
+        c := find(n, 0)
+        switch c.pegRule {
+        case ruleNOT:
+            return b.negatedPath(c.next)
+        case ruleLPAREN:
+            return b.path(find(n, rulepath))
+        }
+        return b.term(c)
+    }
+    return nil
+}
+
+// negatedPath returns the negated property set of the pathNegatedPropertySet node
+func (b *builder) negatedPath(n *node32) Path {
+    np := &PathNegated{}
+    for _, c := range children(n) {
+        if c.pegRule != rulepathOneInPropertySet {
+            continue
+        }
+        if find(c, ruleINVERSE) != nil {
+            np.Inverse = append(np.Inverse, b.term(find(c, 0).next))
+        } else {
+            np.Forward = append(np.Forward, b.term(find(c, 0)))
+        }
+    }
+    return np
+}
+
+// expression returns the expression of the node
+func (b *builder) expression(n *node32) Expression {
+    cs := children(n)
+    switch n.pegRule {
+    case ruleexpression, ruleprimaryExpression, ruleconstraint:
+        return b.expression(cs[0])
+    case rulebrackettedExpression:
+        return b.expression(find(n, ruleexpression))
+    case ruleconditionalOrExpression, ruleconditionalAndExpression:
+        if len(cs) == 1 {
+            return b.expression(cs[0])
+        }
+        return &BinaryExpression{ b.text(cs[1]), b.expression(cs[0]), b.expression(cs[2]) }
+    case rulevalueLogical:
+        e := b.expression(cs[0])
+        switch {
+        case len(cs) == 1:
+            return e
+        case cs[1].pegRule == rulein, cs[1].pegRule == rulenotin:
+            return &InExpression{ cs[1].pegRule == rulenotin, e, b.args(find(cs[1], ruleargList)) }
+        }
+        return &BinaryExpression{ b.text(cs[1]), e, b.expression(cs[2]) }
+    case rulenumericExpression, rulemultiplicativeExpression:
+        e := b.expression(cs[0])
+        for i := 1; i < len(cs); i++ {
+            // a signed number is added, e.g., ?x -1
+            if cs[i].pegRule == rulesignedNumericLiteral {
+                e = &BinaryExpression{ "+", e, numeric(b.text(cs[i])) }
+                continue
+            }
+            e = &BinaryExpression{ b.text(cs[i]), e, b.expression(cs[i + 1]) }
+            i++
+        }
+        return e
+    case ruleunaryExpression:
+        if len(cs) == 1 {
+            return b.expression(cs[0])
+        }
+        return &UnaryExpression{ b.text(cs[0]), b.expression(cs[1]) }
+    case rulefunctionCall:
+        return &FunctionCall{ b.term(find(n, ruleiriref)), b.args(find(n, ruleargList)) }
+    case rulebuiltinCall:
+        switch cs[0].pegRule {
+        case ruleEXISTS, ruleNOTEXIST:
+            return &Exists{ cs[0].pegRule == ruleNOTEXIST, b.group(find(n, rulegroupGraphPattern)) }
+        }
+        call := &BuiltinCall{ Name : strings.ToUpper(b.text(cs[0])) }
+        for _, c := range cs[1:] {
+            switch c.pegRule {
+            case ruleexpression, rulevar:
+                call.Args = append(call.Args, b.expression(c))
+            case ruleargList:
+                call.Args = b.args(c)
+            }
+        }
+        return call
+    case ruleaggregate:
+        if c := find(n, rulecount); c != nil {
+            n, cs = c, children(c)
+        } else if c := find(n, rulegroupConcat); c != nil {
+            n, cs = c, children(c)
+        }
+        a := &Aggregate{ Name : strings.ToUpper(b.text(cs[0])) }
+        if find(n, ruleDISTINCT) != nil {
+            a.Distinct = true
+        }
+        if e := find(n, ruleexpression); e != nil {
+            a.Expression = b.expression(e)
+        }
+        if s := find(n, rulestring); s != nil {
+            a.Separator = literal(b.text(s), "").Value
+        }
+        return a
+    }
+    return b.term(n)
+}
+
+// args returns the expressions of the argList node
+func (b *builder) args(n *node32) []Expression {
+    var args []Expression
+    for _, c := range children(n) {
+        if c.pegRule == ruleexpression {
+            args = append(args, b.expression(c))
+        }
+    }
+    return args
+}
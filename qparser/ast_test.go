@@ -0,0 +1,170 @@
+package qparser
+
+import (
+    "reflect"
+    "testing"
+)
+
+func parse(t *testing.T, query string) *Query {
+    q, err := ParseQuery(query)
+    if err != nil {
+        t.Fatalf("Failed to parse query\n%v", err)
+    }
+    return q
+}
+
+func iri(s string) Term {
+    return Term{ Kind : IRI, Value : s }
+}
+
+func TestParseQuery(t *testing.T) {
+    q := parse(t, `
+    PREFIX foaf: <http://xmlns.com/foaf/0.1/>
+    SELECT DISTINCT ?name (count(?friend) AS ?friends)
+    FROM <http://example.org/g>
+    FROM NAMED <http://example.org/h>
+    WHERE {
+        ?s foaf:name ?name ; foaf:knows ?friend .
+        FILTER (lang(?name) = "en")
+    }
+    GROUP BY ?name
+    HAVING (count(?friend) > 2)
+    ORDER BY desc(?friends)
+    LIMIT 10
+    `)
+    name, friend, friends := variable("?name"), variable("?friend"), variable("?friends")
+    count := &Aggregate{ Name : "COUNT", Expression : friend }
+    expected := &Query{
+        Prefixes : map[string]string{ "foaf" : "http://xmlns.com/foaf/0.1/" },
+        Form : "select",
+        Distinct : true,
+        Projection : []Projection{ { Expression : name }, { count, friends } },
+        From : []Term{ iri("http://example.org/g") },
+        FromNamed : []Term{ iri("http://example.org/h") },
+        Where : &GroupPattern{ []Pattern{
+            &BasicGraphPattern{ []PathPattern{
+                { variable("?s"), iri("http://xmlns.com/foaf/0.1/name"), name },
+                { variable("?s"), iri("http://xmlns.com/foaf/0.1/knows"), friend },
+            } },
+            &Filter{ &BinaryExpression{ "=", &BuiltinCall{ "LANG", []Expression{ name } }, Term{ Kind : Literal, Value : "en" } } },
+        } },
+        GroupBy : []Projection{ { Expression : name } },
+        Having : []Expression{ &BinaryExpression{ ">", count, Term{ Kind : Literal, Value : "2", Datatype : xsdInteger } } },
+        OrderBy : []OrderCondition{ { friends, true } },
+        Limit : 10,
+        Offset : -1,
+    }
+    if !reflect.DeepEqual(expected, q) {
+        t.Errorf("Expected %#v, but got %#v", expected, q)
+    }
+}
+
+func TestParseGraphPatterns(t *testing.T) {
+    q := parse(t, `
+    SELECT * WHERE {
+        ?s <p> ?o
+        OPTIONAL { ?o <q> ?x }
+        { ?s <r> ?y } UNION { ?s <t> ?y } UNION { ?s <u> ?y }
+        MINUS { ?s <v> ?z }
+        GRAPH ?g { ?s <w> ?z }
+        SERVICE SILENT <http://example.org/sparql> { ?s <x> ?z }
+        { SELECT ?s { ?s <y> ?z } }
+        BIND (?o + 1 AS ?n)
+        FILTER NOT EXISTS { ?s <z> ?o }
+    }
+    `)
+    s, o, y, z := variable("?s"), variable("?o"), variable("?y"), variable("?z")
+    group := func(p string, o Term) *GroupPattern {
+        return &GroupPattern{ []Pattern{ &BasicGraphPattern{ []PathPattern{ { s, iri(p), o } } } } }
+    }
+    expected := &GroupPattern{ []Pattern{
+        &BasicGraphPattern{ []PathPattern{ { s, iri("p"), o } } },
+        &Optional{ &GroupPattern{ []Pattern{ &BasicGraphPattern{ []PathPattern{ { o, iri("q"), variable("?x") } } } } } },
+        &Union{ []*GroupPattern{ group("r", y), group("t", y), group("u", y) } },
+        &Minus{ group("v", z) },
+        &Graph{ variable("?g"), group("w", z) },
+        &Service{ true, iri("http://example.org/sparql"), group("x", z) },
+        &GroupPattern{ []Pattern{ &Query{
+            Form : "select",
+            Projection : []Projection{ { Expression : s } },
+            Where : group("y", z),
+            Limit : -1,
+            Offset : -1,
+        } } },
+        &Bind{ &BinaryExpression{ "+", o, Term{ Kind : Literal, Value : "1", Datatype : xsdInteger } }, variable("?n") },
+        &Filter{ &Exists{ true, group("z", o) } },
+    } }
+    if !reflect.DeepEqual(expected, q.Where) {
+        t.Errorf("Expected %#v, but got %#v", expected, q.Where)
+    }
+    var predicates []Path
+    for _, pp := range q.Triples() {
+        predicates = append(predicates, pp.Path)
+    }
+    if expected := []Path{ iri("p"), iri("q"), iri("r"), iri("t"), iri("u"), iri("v"), iri("w"), iri("x"), iri("y"), iri("z") }; !reflect.DeepEqual(expected, predicates) {
+        t.Errorf("Expected the triple patterns of %v, but got %v", expected, predicates)
+    }
+}
+
+func TestParseTriples(t *testing.T) {
+    q := parse(t, `
+    CONSTRUCT { ?s <p> [ <q> "a"@en ] }
+    WHERE {
+        ?s <p>/^<q>|!(<r>|^<t>) ( 1 ?o ) .
+    }
+    `)
+    b0, b1, b2 := Term{ Kind : BlankNode, Value : "b0" }, Term{ Kind : BlankNode, Value : "b1" }, Term{ Kind : BlankNode, Value : "b2" }
+    s := variable("?s")
+    template := []TriplePattern{
+        { b0, iri("q"), Term{ Kind : Literal, Value : "a", Lang : "en" } },
+        { s, iri("p"), b0 },
+    }
+    if !reflect.DeepEqual(template, q.Template) {
+        t.Errorf("Expected %v, but got %v", template, q.Template)
+    }
+    path := &PathAlternative{ []Path{
+        &PathSequence{ []Path{ iri("p"), &PathInverse{ iri("q") } } },
+        &PathNegated{ []Term{ iri("r") }, []Term{ iri("t") } },
+    } }
+    triples := []PathPattern{
+        { b1, rdfFirst, Term{ Kind : Literal, Value : "1", Datatype : xsdInteger } },
+        { b1, rdfRest, b2 },
+        { b2, rdfFirst, variable("?o") },
+        { b2, rdfRest, rdfNil },
+        { s, path, b1 },
+    }
+    expected := &GroupPattern{ []Pattern{ &BasicGraphPattern{ triples } } }
+    if !reflect.DeepEqual(expected, q.Where) {
+        t.Errorf("Expected %#v, but got %#v", expected, q.Where)
+    }
+    if _, ok := triples[4].Triple(); ok {
+        t.Errorf("Expected the property path not to be a triple pattern")
+    }
+}
+
+func TestParsePrologue(t *testing.T) {
+    q := parse(t, `
+    BASE <http://a.org/>
+    PREFIX x: <y/>
+    PREFIX : <z#>
+    BASE <http://b.org/>
+    PREFIX w: <w/>
+    SELECT * { ?s x:p <q> ; :r w:t }
+    `)
+    prefixes := map[string]string{ "x" : "http://a.org/y/", "" : "http://a.org/z#", "w" : "http://b.org/w/" }
+    if q.Base != "http://b.org/" || !reflect.DeepEqual(prefixes, q.Prefixes) {
+        t.Errorf("Unexpected prologue %v %v", q.Base, q.Prefixes)
+    }
+    s := variable("?s")
+    expected := &GroupPattern{ []Pattern{ &BasicGraphPattern{ []PathPattern{
+        { s, iri("http://a.org/y/p"), iri("http://b.org/q") },
+        { s, iri("http://a.org/z#r"), iri("http://b.org/w/t") },
+    } } } }
+    if !reflect.DeepEqual(expected, q.Where) {
+        t.Errorf("Expected %#v, but got %#v", expected, q.Where)
+    }
+    var zero Term
+    if zero.Kind != Invalid || zero.String() != "" {
+        t.Errorf("Expected the zero term to be invalid, got %v %q", zero.Kind, zero)
+    }
+}
@@ -2,22 +2,46 @@
 package qparser
 
 import (
+    "net/url"
     "strconv"
     "strings"
-    "regexp"
     "sort"
 )
 
 // ConnectedComponent represents a part of the SPARQL query
 // where all variables are connected to each other.
 type ConnectedComponent struct {
-    // The patterns of the connected component
+    // The triple patterns of the connected component, sorted, the subjects and
+    // objects being replaced with variables except the classes of rdf:type.
+    // The variables are numbered from v0 in each component.
+    Patterns []TriplePattern
+    // The patterns of the connected component, rendered one per line
     Body string
     // Complexity informs how many stars there are
     // and their number of triple patterns
     Complexity []int
+    // Context is the operators of the group graph patterns enclosing the
+    // component, outermost first, e.g., [graph optional]. It is empty if the
+    // component is in the required part of the query.
+    Context []Operator
 }
 
+// Operator is the operator of a group graph pattern
+type Operator string
+
+// The operators of the group graph patterns
+const (
+    OptionalOp Operator = "optional"
+    // UnionOp is the operator of each branch of a UNION
+    UnionOp Operator = "union"
+    MinusOp Operator = "minus"
+    GraphOp Operator = "graph"
+    ServiceOp Operator = "service"
+    // ExistsOp is the operator of EXISTS and NOT EXISTS
+    ExistsOp Operator = "exists"
+    SubQueryOp Operator = "subquery"
+)
+
 // ConnectedComponents is a list of ConnectedComponent
 type ConnectedComponents []ConnectedComponent
 
@@ -26,7 +50,16 @@ func (ccs ConnectedComponents) Len() int {
 }
 
 func (ccs ConnectedComponents) Less(i, j int) bool {
-    return ccs[i].Body < ccs[j].Body
+    if ccs[i].Body != ccs[j].Body {
+        return ccs[i].Body < ccs[j].Body
+    }
+    // the same component in several scopes
+    for k := 0; k < len(ccs[i].Context) && k < len(ccs[j].Context); k++ {
+        if ccs[i].Context[k] != ccs[j].Context[k] {
+            return ccs[i].Context[k] < ccs[j].Context[k]
+        }
+    }
+    return len(ccs[i].Context) < len(ccs[j].Context)
 }
 
 func (ccs ConnectedComponents) Swap(i, j int) {
@@ -38,18 +71,180 @@ func (ccs ConnectedComponents) Swap(i, j int) {
 // Schema contains the structure of the SPARQL query,
 // which consists in its predicates and classes.
 type schema struct {
-    sts map[string]map[string][]string
+    // the variables replacing the subjects and objects of the triple
+    // patterns, named in the order of the query
     cnt int
-    vars map[string]string
+    vars map[Term]Term
+    // the namespaces of the prefixes declared by the query
+    prefixes map[string]string
+    // the base IRI declared by the query, if any
+    base string
+    // the blank nodes of the query, by label, and the number of blank nodes
+    blankNodes map[string]Term
+    blanks int
+    // the blank node property lists and collections being parsed
+    nodes []node
+}
+
+// node is a blank node property list or a collection being parsed
+type node struct {
+    // the subject and predicate of the enclosing triple pattern
+    s, p Term
+    // the number of items of the collection, and its first and last blank nodes
+    items int
+    head, last Term
 }
 
 func Newschema() *schema {
     s := schema{}
-    s.sts = make(map[string]map[string][]string)
-    s.vars = make(map[string]string)
+    s.vars = make(map[Term]Term)
+    s.prefixes = make(map[string]string)
+    s.blankNodes = make(map[string]Term)
     return &s
 }
 
+// DBpediaPrefixes are the namespaces the DBpedia endpoint declares implicitly
+var DBpediaPrefixes = map[string]string{
+    "rdf" : "http://www.w3.org/1999/02/22-rdf-syntax-ns#",
+    "rdfs" : "http://www.w3.org/2000/01/rdf-schema#",
+    "owl" : "http://www.w3.org/2002/07/owl#",
+    "xsd" : "http://www.w3.org/2001/XMLSchema#",
+    "foaf" : "http://xmlns.com/foaf/0.1/",
+    "dc" : "http://purl.org/dc/elements/1.1/",
+    "dct" : "http://purl.org/dc/terms/",
+    "skos" : "http://www.w3.org/2004/02/skos/core#",
+    "geo" : "http://www.w3.org/2003/01/geo/wgs84_pos#",
+    "georss" : "http://www.georss.org/georss/",
+    "prov" : "http://www.w3.org/ns/prov#",
+    "schema" : "http://schema.org/",
+    "yago" : "http://dbpedia.org/class/yago/",
+    "dbo" : "http://dbpedia.org/ontology/",
+    "dbp" : "http://dbpedia.org/property/",
+    "dbr" : "http://dbpedia.org/resource/",
+    "dbc" : "http://dbpedia.org/resource/Category:",
+    "dbpedia" : "http://dbpedia.org/resource/",
+    "dbpedia-owl" : "http://dbpedia.org/ontology/",
+    "dbpprop" : "http://dbpedia.org/property/",
+    "category" : "http://dbpedia.org/resource/Category:",
+}
+
+// addPrefix declares the prefix of the namespace iri
+func (s *schema) addPrefix(prefix string, iri Term) {
+    s.prefixes[prefix] = iri.Value
+}
+
+// resolve returns the IRI, written between angle brackets, resolved against
+// the base IRI if it is relative
+func (s *schema) resolve(iri string) Term {
+    t := Term{ Kind : IRI, Value : iri[1:len(iri) - 1] }
+    if s.base == "" {
+        return t
+    }
+    ref, err := url.Parse(t.Value)
+    if err != nil || ref.IsAbs() {
+        return t
+    }
+    base, err := url.Parse(s.base)
+    if err != nil {
+        return t
+    }
+    resolved := base.ResolveReference(ref).String()
+    // an empty fragment is dropped by url, e.g., in the namespace <vocab#>
+    if strings.HasSuffix(t.Value, "#") {
+        resolved += "#"
+    }
+    return Term{ Kind : IRI, Value : resolved }
+}
+
+// expandPrefixedName returns the IRI of the prefixed name, e.g., foaf:name,
+// with the namespace declared by the query or else given in Prefixes. A name
+// with an unknown prefix is kept as is.
+func (p *SparqlGraph) expandPrefixedName(name string) Term {
+    return p.expand(name, p.Prefixes)
+}
+
+// expand returns the IRI of the prefixed name, with the namespace declared so
+// far or else given in implicit
+func (s *schema) expand(name string, implicit map[string]string) Term {
+    ind := strings.IndexByte(name, ':')
+    prefix, local := name[:ind], name[ind + 1:]
+    ns, ok := s.prefixes[prefix]
+    if !ok {
+        if ns, ok = implicit[prefix]; !ok {
+            return Term{ Kind : IRI, Value : name }
+        }
+    }
+    // the reserved characters of a local name are escaped with a backslash
+    var sb strings.Builder
+    for i := 0; i < len(local); i++ {
+        if local[i] == '\\' && i + 1 < len(local) {
+            i++
+        }
+        sb.WriteByte(local[i])
+    }
+    return Term{ Kind : IRI, Value : ns + sb.String() }
+}
+
+// blankNode returns the blank node of the label, or a fresh blank node if the
+// label is empty, e.g., for [] and the nodes of collections. The blank nodes
+// are renamed so that the fresh ones do not clash with the labelled ones.
+func (s *schema) blankNode(label string) Term {
+    if b, ok := s.blankNodes[label]; ok {
+        return b
+    }
+    b := Term{ Kind : BlankNode, Value : "b" + strconv.Itoa(s.blanks) }
+    s.blanks++
+    if label != "" {
+        s.blankNodes[label] = b
+    }
+    return b
+}
+
+// beginBlankNodePropertyList starts the triple patterns of a fresh blank node
+func (p *SparqlGraph) beginBlankNodePropertyList() {
+    p.nodes = append(p.nodes, node{ s : p.s, p : p.p })
+    p.s = p.blankNode("")
+}
+
+// endBlankNodePropertyList returns the blank node of the property list, and
+// goes back to the enclosing triple pattern
+func (p *SparqlGraph) endBlankNodePropertyList() Term {
+    b := p.s
+    n := p.nodes[len(p.nodes) - 1]
+    p.nodes = p.nodes[:len(p.nodes) - 1]
+    p.s, p.p = n.s, n.p
+    return b
+}
+
+// beginCollection starts an RDF collection
+func (p *SparqlGraph) beginCollection() {
+    p.nodes = append(p.nodes, node{ s : p.s, p : p.p })
+}
+
+// addCollectionItem adds the item to the collection, linked from a fresh blank node
+func (p *SparqlGraph) addCollectionItem(item Term) {
+    n := &p.nodes[len(p.nodes) - 1]
+    b := p.blankNode("")
+    if n.items == 0 {
+        n.head = b
+    } else {
+        p.addStatement(n.last, rdfRest, b)
+    }
+    p.addStatement(b, rdfFirst, item)
+    n.items++
+    n.last = b
+}
+
+// endCollection returns the first blank node of the collection, and goes
+// back to the enclosing triple pattern
+func (p *SparqlGraph) endCollection() Term {
+    n := p.nodes[len(p.nodes) - 1]
+    p.nodes = p.nodes[:len(p.nodes) - 1]
+    p.addStatement(n.last, rdfRest, rdfNil)
+    p.s, p.p = n.s, n.p
+    return n.head
+}
+
 // Reset initialises the SparqlGraph with the given SPARQL query
 func Reset(sg *SparqlGraph, query string) {
     sg.schema = Newschema()
@@ -57,49 +252,142 @@ func Reset(sg *SparqlGraph, query string) {
     sg.Init()
 }
 
-// GetVar returns a new variable name for the given term,
+// The rules matching no part of the query, or only whitespace and comments
+var layoutRules = map[string]bool{ "Unknown" : true, "skip" : true, "ws" : true, "comment" : true, "endOfLine" : true }
+
+// ParseErrorPosition returns where the parse error returned by Parse occurred,
+// i.e., the line and symbol, starting at 1, of the first symbol following the
+// longest text matched by the grammar, and the last rule which matched. It returns false if err is not
+// a parse error.
+func ParseErrorPosition(err error) (line, symbol int, rule string, ok bool) {
+    pe, ok := err.(*parseError)
+    if !ok {
+        return 0, 0, "", false
+    }
+    end := -1
+    for _, token := range pe.p.tokenTree.Error() {
+        name := rul3s[token.pegRule]
+        if layoutRules[name] || strings.HasPrefix(name, "Action") {
+            continue
+        }
+        if int(token.end) > end {
+            end, rule = int(token.end), name
+        }
+    }
+    if end == -1 {
+        end = 0
+    }
+    line, symbol = 1, 1
+    for _, c := range pe.p.buffer[:end] {
+        if c == '\n' {
+            line, symbol = line + 1, 1
+        } else {
+            symbol++
+        }
+    }
+    return line, symbol, rule, true
+}
+
+// BGPCount returns the number of basic graph patterns of the parsed query
+// holding triple patterns, a basic graph pattern being a sequence of triple
+// patterns and filters in a group.
+func (p *SparqlGraph) BGPCount() int {
+    count, found := 0, false
+    var triples token32
+    // the tokens of a rule come after the tokens of its sub-rules
+    for token := range p.tokenTree.Tokens() {
+        switch token.pegRule {
+        case ruletriplesBlock:
+            triples, found = token, true
+        case rulebasicGraphPattern:
+            if found && token.begin <= triples.begin && triples.end <= token.end {
+                count++
+            }
+        }
+    }
+    return count
+}
+
+// QueryForm returns the form of the parsed query, i.e., select, construct,
+// describe or ask, or an empty string if the query was not parsed
+func (p *SparqlGraph) QueryForm() string {
+    for token := range p.tokenTree.Tokens() {
+        switch token.pegRule {
+        case ruleselectQuery:
+            return "select"
+        case ruleconstructQuery:
+            return "construct"
+        case ruledescribeQuery:
+            return "describe"
+        case ruleaskQuery:
+            return "ask"
+        }
+    }
+    return ""
+}
+
+// GetVar returns a new variable for the given term,
 // either a literal, a bnode, a uri, or a variable.
-func (s *schema) getVar(str string) string {
-    if v,ok := s.vars[str]; ok {
+func (s *schema) getVar(t Term) Term {
+    if v,ok := s.vars[t]; ok {
         return v
     }
-    strVar := "?v" + strconv.Itoa(s.cnt)
-    s.vars[str] = strVar
+    v := Term{ Kind : Variable, Value : "v" + strconv.Itoa(s.cnt) }
+    s.vars[t] = v
     s.cnt++
-    return strVar
+    return v
 }
 
-// AddStatements adds the spo triple pattern to the query's schema
-func (schema *schema) addStatement(s, p, o string) {
-    if p[0] == '?' {
+// AddStatements names the variables of the spo triple pattern, the
+// components being computed from the syntax tree of the query
+func (schema *schema) addStatement(s, p, o Term) {
+    if p.Kind == Variable {
         return
     }
-    s = schema.getVar(s)
-    if p != "<http://www.w3.org/1999/02/22-rdf-syntax-ns#type>" {
-        o = schema.getVar(o)
+    schema.getVar(s)
+    if p != rdfType {
+        schema.getVar(o)
     }
-    if _, ok := schema.sts[s]; ok {
-        if _, ok := schema.sts[s][p]; ok {
-            for _, o2 := range schema.sts[s][p] {
-                if o == o2 {
-                    return
+}
+
+// statements returns the triple patterns, whose subjects and objects are
+// replaced with variables except the classes of rdf:type, by subject and predicate
+func (schema *schema) statements(triples []TriplePattern) map[Term]map[Term][]Term {
+    sts := make(map[Term]map[Term][]Term)
+    for _, tp := range triples {
+        s, p, o := tp.Subject, tp.Predicate, tp.Object
+        if p.Kind == Variable {
+            continue
+        }
+        s = schema.getVar(s)
+        if p != rdfType {
+            o = schema.getVar(o)
+        }
+        if _, ok := sts[s]; ok {
+            if _, ok := sts[s][p]; ok {
+                found := false
+                for _, o2 := range sts[s][p] {
+                    found = found || o == o2
+                }
+                if !found {
+                    sts[s][p] = append(sts[s][p], o)
                 }
+            } else {
+                sts[s][p] = []Term{ o }
             }
-            schema.sts[s][p] = append(schema.sts[s][p], o)
         } else {
-            schema.sts[s][p] = []string{ o }
-        }
-    } else {
-        schema.sts[s] = map[string][]string {
-            p : []string{ o },
+            sts[s] = map[Term][]Term {
+                p : []Term{ o },
+            }
         }
     }
+    return sts
 }
 
 // GetKey returns the key associated with the SPARQL variable,
 // and a boolean indicating if a key was found.
 // A key is a string which the variable is a substring of.
-func getKey(varName string, ccs map[string][]string) (string, bool) {
+func getKey(varName string, ccs map[string][]TriplePattern) (string, bool) {
     for k := range ccs {
         if strings.Contains(k, varName) {
             return k, true
@@ -109,28 +397,141 @@ func getKey(varName string, ccs map[string][]string) (string, bool) {
 }
 
 // ConnectedComponents returns the connected components of the SPARQL query.
-func (schema *schema) ConnectedComponents() (ar ConnectedComponents) {
+// The components of each group graph pattern are computed apart, a group
+// nested without an operator being joined with the enclosing group.
+func (p *SparqlGraph) ConnectedComponents() (ar ConnectedComponents) {
+    q := p.Query()
+    if q == nil || q.Where == nil {
+        return
+    }
+    p.scope(q.Where, nil, &ar)
+    sort.Sort(ar)
+    return
+}
+
+// scope appends the connected components of the group, whose operators are
+// the context, and of the groups under it
+func (p *SparqlGraph) scope(g *GroupPattern, context []Operator, ar *ConnectedComponents) {
+    for _, cc := range p.components(p.join(g, context, ar)) {
+        cc.Context = context
+        *ar = append(*ar, cc)
+    }
+}
+
+// join returns the triple patterns of the group, and of the groups nested in
+// it without an operator. The components of the other groups are appended.
+func (p *SparqlGraph) join(g *GroupPattern, context []Operator, ar *ConnectedComponents) []TriplePattern {
+    var triples []TriplePattern
+    // the context of a nested group, which does not share the array of context
+    nested := func(op Operator) []Operator {
+        return append(context[:len(context):len(context)], op)
+    }
+    for _, pt := range g.Patterns {
+        switch pt := pt.(type) {
+        case *BasicGraphPattern:
+            for _, pp := range pt.Triples {
+                triples = append(triples, TriplePattern{ pp.Subject, predicate(pp.Path), pp.Object })
+            }
+        case *GroupPattern:
+            triples = append(triples, p.join(pt, context, ar)...)
+        case *Union:
+            for _, branch := range pt.Branches {
+                p.scope(branch, nested(UnionOp), ar)
+            }
+        case *Optional:
+            p.scope(pt.Pattern, nested(OptionalOp), ar)
+        case *Minus:
+            p.scope(pt.Pattern, nested(MinusOp), ar)
+        case *Graph:
+            p.scope(pt.Pattern, nested(GraphOp), ar)
+        case *Service:
+            p.scope(pt.Pattern, nested(ServiceOp), ar)
+        case *Query:
+            p.scope(pt.Where, nested(SubQueryOp), ar)
+        case *Filter:
+            p.exists(pt.Expression, nested(ExistsOp), ar)
+        case *Bind:
+            p.exists(pt.Expression, nested(ExistsOp), ar)
+        }
+    }
+    return triples
+}
+
+// exists appends the connected components of the EXISTS and NOT EXISTS of the expression
+func (p *SparqlGraph) exists(e Expression, context []Operator, ar *ConnectedComponents) {
+    switch e := e.(type) {
+    case *Exists:
+        p.scope(e.Pattern, context, ar)
+    case *BinaryExpression:
+        p.exists(e.Left, context, ar)
+        p.exists(e.Right, context, ar)
+    case *UnaryExpression:
+        p.exists(e.Expression, context, ar)
+    case *InExpression:
+        p.exists(e.Expression, context, ar)
+        for _, arg := range e.List {
+            p.exists(arg, context, ar)
+        }
+    case *FunctionCall:
+        for _, arg := range e.Args {
+            p.exists(arg, context, ar)
+        }
+    case *BuiltinCall:
+        for _, arg := range e.Args {
+            p.exists(arg, context, ar)
+        }
+    }
+}
+
+// predicate returns the predicate of a triple pattern whose predicate is the
+// path, i.e., the last IRI of the path as the semantic actions read it
+func predicate(path Path) Term {
+    switch pt := path.(type) {
+    case Term:
+        return pt
+    case *PathAlternative:
+        return predicate(pt.Paths[len(pt.Paths) - 1])
+    case *PathSequence:
+        return predicate(pt.Paths[len(pt.Paths) - 1])
+    case *PathInverse:
+        return predicate(pt.Path)
+    case *PathMod:
+        return predicate(pt.Path)
+    case *PathNegated:
+        if len(pt.Inverse) != 0 {
+            return pt.Inverse[len(pt.Inverse) - 1]
+        }
+        if len(pt.Forward) != 0 {
+            return pt.Forward[len(pt.Forward) - 1]
+        }
+    }
+    return Term{ Kind : Variable }
+}
+
+// components returns the connected components of the triple patterns
+func (schema *schema) components(triples []TriplePattern) (ar ConnectedComponents) {
     // map of connected components
     // the key is the set of variables part of a component
-    ccs := make(map[string][]string)
-    for s, pos := range schema.sts {
-        var cc []string
-        key, _ := getKey(s + "-", ccs)
+    ccs := make(map[string][]TriplePattern)
+    for s, pos := range schema.statements(triples) {
+        var cc []TriplePattern
+        key, _ := getKey(s.String() + "-", ccs)
         for p, os := range pos {
             for _, o := range os {
-                if o[0] == '?' {
-                    newkey, ok := getKey(o + "-", ccs)
+                if o.Kind == Variable {
+                    newkey, ok := getKey(o.String() + "-", ccs)
                     if newkey != key {
                         cc = append(cc, ccs[key]...)
                         delete(ccs, key)
-                        if !ok {
-                            key += newkey
-                        } else {
-                            key = newkey
+                        // the key keeps the variables of both components
+                        if ok {
+                            cc = append(cc, ccs[newkey]...)
+                            delete(ccs, newkey)
                         }
+                        key += newkey
                     }
                 }
-                cc = append(cc, "    " + s + " " + p + " " + o + " .")
+                cc = append(cc, TriplePattern{ s, p, o })
             }
         }
         ccs[key] = append(ccs[key], cc...)
@@ -138,27 +539,85 @@ func (schema *schema) ConnectedComponents() (ar ConnectedComponents) {
     if len(ccs) == 0 {
         return
     }
-    reg := regexp.MustCompile("    \\?[^ ]+")
     for _, v := range ccs {
-        sort.Strings(v)
-        body := strings.Join(v, "\n") + "\n"
-        cc := ConnectedComponent{ Body : body }
-
-        m := reg.FindAllStringIndex(body, -1)
-        prev, cnt := "", 0
-        for _, ind := range m {
-            if cnt != 0 && prev != body[ind[0]:ind[1]] {
+        v = schema.renumber(v)
+        // the patterns are sorted as rendered, which groups them by subject
+        lines := make([]string, len(v))
+        for i := range v {
+            lines[i] = "    " + v[i].String()
+        }
+        sort.Sort(byLine{ lines, v })
+        cc := ConnectedComponent{ Patterns : v, Body : strings.Join(lines, "\n") + "\n" }
+
+        prev, cnt := Term{}, 0
+        for _, tp := range v {
+            if cnt != 0 && prev != tp.Subject {
                 cc.Complexity = append(cc.Complexity, cnt)
                 cnt = 0
             }
             cnt++
-            prev = body[ind[0]:ind[1]]
+            prev = tp.Subject
         }
         cc.Complexity = append(cc.Complexity, cnt)
         sort.Ints(cc.Complexity)
         ar = append(ar, cc)
     }
-    sort.Sort(ar)
     return
 }
 
+// renumber returns the triple patterns of a component, whose variables are
+// renamed v0, v1, ... in the order the query names them. A component is so
+// written the same wherever it is in the query.
+func (schema *schema) renumber(triples []TriplePattern) []TriplePattern {
+    // the variables which replace the subjects and objects, by number
+    named := make(map[Term]int)
+    for _, v := range schema.vars {
+        named[v], _ = strconv.Atoi(v.Value[1:])
+    }
+    var vars []Term
+    renamed := make(map[Term]Term)
+    for _, tp := range triples {
+        for _, t := range []Term{ tp.Subject, tp.Object } {
+            if _, ok := named[t]; ok && renamed[t] == (Term{}) {
+                renamed[t] = t
+                vars = append(vars, t)
+            }
+        }
+    }
+    sort.Slice(vars, func(i, j int) bool {
+        return named[vars[i]] < named[vars[j]]
+    })
+    for i, v := range vars {
+        renamed[v] = Term{ Kind : Variable, Value : "v" + strconv.Itoa(i) }
+    }
+    ar := make([]TriplePattern, len(triples))
+    for i, tp := range triples {
+        ar[i] = tp
+        if v, ok := renamed[tp.Subject]; ok {
+            ar[i].Subject = v
+        }
+        if v, ok := renamed[tp.Object]; ok {
+            ar[i].Object = v
+        }
+    }
+    return ar
+}
+
+// byLine sorts triple patterns by their rendered lines
+type byLine struct {
+    lines []string
+    patterns []TriplePattern
+}
+
+func (b byLine) Len() int {
+    return len(b.lines)
+}
+
+func (b byLine) Less(i, j int) bool {
+    return b.lines[i] < b.lines[j]
+}
+
+func (b byLine) Swap(i, j int) {
+    b.lines[i], b.lines[j] = b.lines[j], b.lines[i]
+    b.patterns[i], b.patterns[j] = b.patterns[j], b.patterns[i]
+}
@@ -246,6 +246,22 @@ const (
 	ruleAction4
 	ruleAction5
 	ruleAction6
+	ruleAction7
+	ruleAction8
+	ruleAction9
+	ruleAction10
+	ruleAction11
+	ruleAction12
+	ruleAction13
+	ruleAction14
+	ruleAction15
+	ruleAction16
+	ruleAction17
+	ruleAction18
+	ruleAction19
+	ruleAction20
+	ruleAction21
+	ruleAction22
 
 	rulePre_
 	rule_In_
@@ -486,6 +502,22 @@ var rul3s = [...]string{
 	"Action4",
 	"Action5",
 	"Action6",
+	"Action7",
+	"Action8",
+	"Action9",
+	"Action10",
+	"Action11",
+	"Action12",
+	"Action13",
+	"Action14",
+	"Action15",
+	"Action16",
+	"Action17",
+	"Action18",
+	"Action19",
+	"Action20",
+	"Action21",
+	"Action22",
 
 	"Pre_",
 	"_In_",
@@ -1051,11 +1083,15 @@ func (t *tokens32) Expand(index int) tokenTree {
 
 type SparqlGraph struct {
 	*schema
-	label, s, p, o string
+	label, s, p, o Term
+	prefix         string
+	// Prefixes holds the namespaces of the prefixes which a query may use
+	// without declaring them, as an endpoint may do, e.g., DBpediaPrefixes
+	Prefixes map[string]string
 
 	Buffer string
 	buffer []rune
-	rules  [233]func() bool
+	rules  [249]func() bool
 	Parse  func(rule ...int) error
 	Reset  func()
 	tokenTree
@@ -1138,13 +1174,45 @@ func (p *SparqlGraph) Execute() {
 			p.o = p.label
 			p.addStatement(p.s, p.p, p.o)
 		case ruleAction3:
-			p.label = buffer[begin:end]
+			p.label = variable(buffer[begin:end])
 		case ruleAction4:
-			p.label = buffer[begin:end]
+			p.label = p.resolve(buffer[begin:end])
 		case ruleAction5:
-			p.label = buffer[begin:end]
+			p.label = literal(buffer[begin:end], p.label.Value)
 		case ruleAction6:
-			p.label = "<http://www.w3.org/1999/02/22-rdf-syntax-ns#type>"
+			p.label = rdfType
+		case ruleAction7:
+			p.prefix = buffer[begin:end]
+		case ruleAction8:
+			p.addPrefix(p.prefix, p.label)
+		case ruleAction9:
+			p.base = p.label.Value
+		case ruleAction10:
+			p.label = p.expandPrefixedName(buffer[begin:end])
+		case ruleAction11:
+			p.s = p.label
+		case ruleAction12:
+			p.beginCollection()
+		case ruleAction13:
+			p.addCollectionItem(p.label)
+		case ruleAction14:
+			p.label = p.endCollection()
+		case ruleAction15:
+			p.beginBlankNodePropertyList()
+		case ruleAction16:
+			p.label = p.endBlankNodePropertyList()
+		case ruleAction17:
+			p.label = numeric(buffer[begin:end])
+		case ruleAction18:
+			p.label = trueLiteral
+		case ruleAction19:
+			p.label = falseLiteral
+		case ruleAction20:
+			p.label = p.blankNode(buffer[begin:end])
+		case ruleAction21:
+			p.label = p.blankNode("")
+		case ruleAction22:
+			p.label = rdfNil
 
 		}
 	}
@@ -1331,15 +1399,24 @@ func (p *SparqlGraph) Init() {
 									add(rulePREFIX, position8)
 								}
 								{
-									position21, tokenIndex21, depth21 := position, tokenIndex, depth
-									if !rules[rulepnPrefix]() {
-										goto l21
+									position1912 := position
+									depth++
+									{
+										position21, tokenIndex21, depth21 := position, tokenIndex, depth
+										if !rules[rulepnPrefix]() {
+											goto l21
+										}
+										goto l22
+									l21:
+										position, tokenIndex, depth = position21, tokenIndex21, depth21
 									}
-									goto l22
-								l21:
-									position, tokenIndex, depth = position21, tokenIndex21, depth21
+								l22:
+									depth--
+									add(rulePegText, position1912)
+								}
+								{
+									add(ruleAction7, position)
 								}
-							l22:
 								{
 									position23 := position
 									depth++
@@ -1356,6 +1433,9 @@ func (p *SparqlGraph) Init() {
 								if !rules[ruleiri]() {
 									goto l6
 								}
+								{
+									add(ruleAction8, position)
+								}
 								depth--
 								add(ruleprefixDecl, position7)
 							}
@@ -1437,6 +1517,9 @@ func (p *SparqlGraph) Init() {
 								if !rules[ruleiri]() {
 									goto l4
 								}
+								{
+									add(ruleAction9, position)
+								}
 								depth--
 								add(rulebaseDecl, position24)
 							}
@@ -1957,9 +2040,9 @@ func (p *SparqlGraph) Init() {
 		},
 		/* 1 prolog <- <(prefixDecl / baseDecl)*> */
 		nil,
-		/* 2 prefixDecl <- <(PREFIX pnPrefix? COLON iri)> */
+		/* 2 prefixDecl <- <(PREFIX <pnPrefix?> Action7 COLON iri Action8)> */
 		nil,
-		/* 3 baseDecl <- <(BASE iri)> */
+		/* 3 baseDecl <- <(BASE iri Action9)> */
 		nil,
 		/* 4 query <- <((&('A' | 'a') askQuery) | (&('D' | 'd') describeQuery) | (&('C' | 'c') constructQuery) | (&('S' | 's') selectQuery))> */
 		nil,
@@ -3799,7 +3882,7 @@ func (p *SparqlGraph) Init() {
 			position, tokenIndex, depth = position359, tokenIndex359, depth359
 			return false
 		},
-		/* 28 triplesSameSubjectPath <- <((<varOrTerm> Action0 propertyListPath) / (triplesNodePath propertyListPath?))> */
+		/* 28 triplesSameSubjectPath <- <((<varOrTerm> Action0 propertyListPath) / (triplesNodePath Action11 propertyListPath?))> */
 		func() bool {
 			position365, tokenIndex365, depth365 := position, tokenIndex, depth
 			{
@@ -3828,6 +3911,9 @@ func (p *SparqlGraph) Init() {
 					if !rules[ruletriplesNodePath]() {
 						goto l365
 					}
+					{
+						add(ruleAction11, position)
+					}
 					{
 						position371, tokenIndex371, depth371 := position, tokenIndex, depth
 						if !rules[rulepropertyListPath]() {
@@ -3889,100 +3975,109 @@ func (p *SparqlGraph) Init() {
 											{
 												position384 := position
 												depth++
-												if buffer[position] != rune('_') {
-													goto l383
-												}
-												position++
-												if buffer[position] != rune(':') {
-													goto l383
-												}
-												position++
 												{
-													position385, tokenIndex385, depth385 := position, tokenIndex, depth
-													if !rules[rulepnCharsU]() {
-														goto l386
+													position1914 := position
+													depth++
+													if buffer[position] != rune('_') {
+														goto l383
 													}
-													goto l385
-												l386:
-													position, tokenIndex, depth = position385, tokenIndex385, depth385
-													if c := buffer[position]; c < rune('0') || c > rune('9') {
+													position++
+													if buffer[position] != rune(':') {
 														goto l383
 													}
 													position++
-												}
-											l385:
-												{
-													position387, tokenIndex387, depth387 := position, tokenIndex, depth
 													{
-														position389, tokenIndex389, depth389 := position, tokenIndex, depth
-													l391:
+														position385, tokenIndex385, depth385 := position, tokenIndex, depth
+														if !rules[rulepnCharsU]() {
+															goto l386
+														}
+														goto l385
+													l386:
+														position, tokenIndex, depth = position385, tokenIndex385, depth385
+														if c := buffer[position]; c < rune('0') || c > rune('9') {
+															goto l383
+														}
+														position++
+													}
+												l385:
+													{
+														position387, tokenIndex387, depth387 := position, tokenIndex, depth
 														{
-															position392, tokenIndex392, depth392 := position, tokenIndex, depth
+															position389, tokenIndex389, depth389 := position, tokenIndex, depth
+														l391:
 															{
-																position393, tokenIndex393, depth393 := position, tokenIndex, depth
-																if !rules[rulepnCharsU]() {
-																	goto l394
-																}
-																goto l393
-															l394:
-																position, tokenIndex, depth = position393, tokenIndex393, depth393
+																position392, tokenIndex392, depth392 := position, tokenIndex, depth
 																{
-																	switch buffer[position] {
-																	case '.':
-																		if buffer[position] != rune('.') {
-																			goto l392
-																		}
-																		position++
-																		break
-																	case '-':
-																		if buffer[position] != rune('-') {
-																			goto l392
-																		}
-																		position++
-																		break
-																	default:
-																		if c := buffer[position]; c < rune('0') || c > rune('9') {
-																			goto l392
+																	position393, tokenIndex393, depth393 := position, tokenIndex, depth
+																	if !rules[rulepnCharsU]() {
+																		goto l394
+																	}
+																	goto l393
+																l394:
+																	position, tokenIndex, depth = position393, tokenIndex393, depth393
+																	{
+																		switch buffer[position] {
+																		case '.':
+																			if buffer[position] != rune('.') {
+																				goto l392
+																			}
+																			position++
+																			break
+																		case '-':
+																			if buffer[position] != rune('-') {
+																				goto l392
+																			}
+																			position++
+																			break
+																		default:
+																			if c := buffer[position]; c < rune('0') || c > rune('9') {
+																				goto l392
+																			}
+																			position++
+																			break
 																		}
-																		position++
-																		break
 																	}
-																}
 
+																}
+															l393:
+																goto l391
+															l392:
+																position, tokenIndex, depth = position392, tokenIndex392, depth392
 															}
-														l393:
-															goto l391
-														l392:
-															position, tokenIndex, depth = position392, tokenIndex392, depth392
-														}
-														if !rules[rulepnCharsU]() {
-															goto l390
-														}
-														goto l389
-													l390:
-														position, tokenIndex, depth = position389, tokenIndex389, depth389
-														{
-															position396, tokenIndex396, depth396 := position, tokenIndex, depth
-															if c := buffer[position]; c < rune('0') || c > rune('9') {
-																goto l397
+															if !rules[rulepnCharsU]() {
+																goto l390
 															}
-															position++
-															goto l396
-														l397:
-															position, tokenIndex, depth = position396, tokenIndex396, depth396
-															if buffer[position] != rune('-') {
-																goto l387
+															goto l389
+														l390:
+															position, tokenIndex, depth = position389, tokenIndex389, depth389
+															{
+																position396, tokenIndex396, depth396 := position, tokenIndex, depth
+																if c := buffer[position]; c < rune('0') || c > rune('9') {
+																	goto l397
+																}
+																position++
+																goto l396
+															l397:
+																position, tokenIndex, depth = position396, tokenIndex396, depth396
+																if buffer[position] != rune('-') {
+																	goto l387
+																}
+																position++
 															}
-															position++
+														l396:
 														}
-													l396:
+													l389:
+														goto l388
+													l387:
+														position, tokenIndex, depth = position387, tokenIndex387, depth387
 													}
-												l389:
-													goto l388
-												l387:
-													position, tokenIndex, depth = position387, tokenIndex387, depth387
+												l388:
+													depth--
+													add(rulePegText, position1914)
+												}
+												{
+													add(ruleAction20, position)
 												}
-											l388:
 												if !rules[ruleskip]() {
 													goto l383
 												}
@@ -4013,6 +4108,9 @@ func (p *SparqlGraph) Init() {
 													goto l373
 												}
 												position++
+												{
+													add(ruleAction21, position)
+												}
 												if !rules[ruleskip]() {
 													goto l373
 												}
@@ -4074,15 +4172,24 @@ func (p *SparqlGraph) Init() {
 						if !rules[ruleLPAREN]() {
 							goto l405
 						}
+						{
+							add(ruleAction12, position)
+						}
 						if !rules[rulegraphNodePath]() {
 							goto l405
 						}
+						{
+							add(ruleAction13, position)
+						}
 					l407:
 						{
 							position408, tokenIndex408, depth408 := position, tokenIndex, depth
 							if !rules[rulegraphNodePath]() {
 								goto l408
 							}
+							{
+								add(ruleAction13, position)
+							}
 							goto l407
 						l408:
 							position, tokenIndex, depth = position408, tokenIndex408, depth408
@@ -4090,6 +4197,9 @@ func (p *SparqlGraph) Init() {
 						if !rules[ruleRPAREN]() {
 							goto l405
 						}
+						{
+							add(ruleAction14, position)
+						}
 						depth--
 						add(rulecollectionPath, position406)
 					}
@@ -4112,6 +4222,9 @@ func (p *SparqlGraph) Init() {
 							depth--
 							add(ruleLBRACK, position410)
 						}
+						{
+							add(ruleAction15, position)
+						}
 						if !rules[rulepropertyListPath]() {
 							goto l402
 						}
@@ -4128,6 +4241,9 @@ func (p *SparqlGraph) Init() {
 							depth--
 							add(ruleRBRACK, position411)
 						}
+						{
+							add(ruleAction16, position)
+						}
 						depth--
 						add(ruleblankNodePropertyListPath, position409)
 					}
@@ -4141,9 +4257,9 @@ func (p *SparqlGraph) Init() {
 			position, tokenIndex, depth = position402, tokenIndex402, depth402
 			return false
 		},
-		/* 32 collectionPath <- <(LPAREN graphNodePath+ RPAREN)> */
+		/* 32 collectionPath <- <(LPAREN Action12 (graphNodePath Action13)+ RPAREN Action14)> */
 		nil,
-		/* 33 blankNodePropertyListPath <- <(LBRACK propertyListPath RBRACK)> */
+		/* 33 blankNodePropertyListPath <- <(LBRACK Action15 propertyListPath RBRACK Action16)> */
 		nil,
 		/* 34 propertyListPath <- <(<(var / verbPath)> Action1 objectListPath (SEMICOLON propertyListPath?)?)> */
 		func() bool {
@@ -4569,7 +4685,7 @@ func (p *SparqlGraph) Init() {
 			position, tokenIndex, depth = position475, tokenIndex475, depth475
 			return false
 		},
-		/* 47 solutionModifier <- <((ORDER BY orderCondition+) / ((&('H' | 'h') (HAVING constraint)) | (&('G' | 'g') (GROUP BY groupCondition+)) | (&('L' | 'O' | 'l' | 'o') limitOffsetClauses)))?> */
+		/* 47 solutionModifier <- <((GROUP BY groupCondition+)? (HAVING constraint)? (ORDER BY orderCondition+)? limitOffsetClauses?)> */
 		func() bool {
 			{
 				position480 := position
@@ -4577,803 +4693,806 @@ func (p *SparqlGraph) Init() {
 				{
 					position481, tokenIndex481, depth481 := position, tokenIndex, depth
 					{
-						position483, tokenIndex483, depth483 := position, tokenIndex, depth
+						position562 := position
+						depth++
 						{
-							position485 := position
-							depth++
-							{
-								position486, tokenIndex486, depth486 := position, tokenIndex, depth
-								if buffer[position] != rune('o') {
-									goto l487
-								}
-								position++
-								goto l486
-							l487:
-								position, tokenIndex, depth = position486, tokenIndex486, depth486
-								if buffer[position] != rune('O') {
-									goto l484
-								}
-								position++
+							position563, tokenIndex563, depth563 := position, tokenIndex, depth
+							if buffer[position] != rune('g') {
+								goto l564
 							}
-						l486:
-							{
-								position488, tokenIndex488, depth488 := position, tokenIndex, depth
-								if buffer[position] != rune('r') {
-									goto l489
-								}
-								position++
-								goto l488
-							l489:
-								position, tokenIndex, depth = position488, tokenIndex488, depth488
-								if buffer[position] != rune('R') {
-									goto l484
-								}
-								position++
+							position++
+							goto l563
+						l564:
+							position, tokenIndex, depth = position563, tokenIndex563, depth563
+							if buffer[position] != rune('G') {
+								goto l481
 							}
-						l488:
-							{
-								position490, tokenIndex490, depth490 := position, tokenIndex, depth
-								if buffer[position] != rune('d') {
-									goto l491
-								}
-								position++
-								goto l490
-							l491:
-								position, tokenIndex, depth = position490, tokenIndex490, depth490
-								if buffer[position] != rune('D') {
-									goto l484
-								}
-								position++
+							position++
+						}
+					l563:
+						{
+							position565, tokenIndex565, depth565 := position, tokenIndex, depth
+							if buffer[position] != rune('r') {
+								goto l566
 							}
-						l490:
-							{
-								position492, tokenIndex492, depth492 := position, tokenIndex, depth
-								if buffer[position] != rune('e') {
-									goto l493
-								}
-								position++
-								goto l492
-							l493:
-								position, tokenIndex, depth = position492, tokenIndex492, depth492
-								if buffer[position] != rune('E') {
-									goto l484
-								}
-								position++
+							position++
+							goto l565
+						l566:
+							position, tokenIndex, depth = position565, tokenIndex565, depth565
+							if buffer[position] != rune('R') {
+								goto l481
 							}
-						l492:
-							{
-								position494, tokenIndex494, depth494 := position, tokenIndex, depth
-								if buffer[position] != rune('r') {
-									goto l495
-								}
-								position++
-								goto l494
-							l495:
-								position, tokenIndex, depth = position494, tokenIndex494, depth494
-								if buffer[position] != rune('R') {
-									goto l484
-								}
-								position++
+							position++
+						}
+					l565:
+						{
+							position567, tokenIndex567, depth567 := position, tokenIndex, depth
+							if buffer[position] != rune('o') {
+								goto l568
 							}
-						l494:
-							if !rules[ruleskip]() {
-								goto l484
+							position++
+							goto l567
+						l568:
+							position, tokenIndex, depth = position567, tokenIndex567, depth567
+							if buffer[position] != rune('O') {
+								goto l481
 							}
-							depth--
-							add(ruleORDER, position485)
+							position++
 						}
-						if !rules[ruleBY]() {
-							goto l484
+					l567:
+						{
+							position569, tokenIndex569, depth569 := position, tokenIndex, depth
+							if buffer[position] != rune('u') {
+								goto l570
+							}
+							position++
+							goto l569
+						l570:
+							position, tokenIndex, depth = position569, tokenIndex569, depth569
+							if buffer[position] != rune('U') {
+								goto l481
+							}
+							position++
 						}
+					l569:
 						{
-							position498 := position
-							depth++
+							position571, tokenIndex571, depth571 := position, tokenIndex, depth
+							if buffer[position] != rune('p') {
+								goto l572
+							}
+							position++
+							goto l571
+						l572:
+							position, tokenIndex, depth = position571, tokenIndex571, depth571
+							if buffer[position] != rune('P') {
+								goto l481
+							}
+							position++
+						}
+					l571:
+						if !rules[ruleskip]() {
+							goto l481
+						}
+						depth--
+						add(ruleGROUP, position562)
+					}
+					if !rules[ruleBY]() {
+						goto l481
+					}
+					{
+						position575 := position
+						depth++
+						{
+							position576, tokenIndex576, depth576 := position, tokenIndex, depth
+							if !rules[rulefunctionCall]() {
+								goto l577
+							}
+							goto l576
+						l577:
+							position, tokenIndex, depth = position576, tokenIndex576, depth576
 							{
-								position499, tokenIndex499, depth499 := position, tokenIndex, depth
-								{
-									position501, tokenIndex501, depth501 := position, tokenIndex, depth
+								switch buffer[position] {
+								case '$', '?':
+									if !rules[rulevar]() {
+										goto l481
+									}
+									break
+								case '(':
+									if !rules[ruleLPAREN]() {
+										goto l481
+									}
+									if !rules[ruleexpression]() {
+										goto l481
+									}
 									{
-										position503, tokenIndex503, depth503 := position, tokenIndex, depth
-										{
-											position505 := position
-											depth++
-											{
-												position506, tokenIndex506, depth506 := position, tokenIndex, depth
-												if buffer[position] != rune('a') {
-													goto l507
-												}
-												position++
-												goto l506
-											l507:
-												position, tokenIndex, depth = position506, tokenIndex506, depth506
-												if buffer[position] != rune('A') {
-													goto l504
-												}
-												position++
-											}
-										l506:
-											{
-												position508, tokenIndex508, depth508 := position, tokenIndex, depth
-												if buffer[position] != rune('s') {
-													goto l509
-												}
-												position++
-												goto l508
-											l509:
-												position, tokenIndex, depth = position508, tokenIndex508, depth508
-												if buffer[position] != rune('S') {
-													goto l504
-												}
-												position++
-											}
-										l508:
-											{
-												position510, tokenIndex510, depth510 := position, tokenIndex, depth
-												if buffer[position] != rune('c') {
-													goto l511
-												}
-												position++
-												goto l510
-											l511:
-												position, tokenIndex, depth = position510, tokenIndex510, depth510
-												if buffer[position] != rune('C') {
-													goto l504
-												}
-												position++
-											}
-										l510:
-											if !rules[ruleskip]() {
-												goto l504
-											}
-											depth--
-											add(ruleASC, position505)
+										position579, tokenIndex579, depth579 := position, tokenIndex, depth
+										if !rules[ruleAS]() {
+											goto l579
 										}
-										goto l503
-									l504:
-										position, tokenIndex, depth = position503, tokenIndex503, depth503
-										{
-											position512 := position
-											depth++
-											{
-												position513, tokenIndex513, depth513 := position, tokenIndex, depth
-												if buffer[position] != rune('d') {
-													goto l514
-												}
-												position++
-												goto l513
-											l514:
-												position, tokenIndex, depth = position513, tokenIndex513, depth513
-												if buffer[position] != rune('D') {
-													goto l501
-												}
-												position++
-											}
-										l513:
-											{
-												position515, tokenIndex515, depth515 := position, tokenIndex, depth
-												if buffer[position] != rune('e') {
-													goto l516
-												}
-												position++
-												goto l515
-											l516:
-												position, tokenIndex, depth = position515, tokenIndex515, depth515
-												if buffer[position] != rune('E') {
-													goto l501
-												}
-												position++
-											}
-										l515:
-											{
-												position517, tokenIndex517, depth517 := position, tokenIndex, depth
-												if buffer[position] != rune('s') {
-													goto l518
-												}
-												position++
-												goto l517
-											l518:
-												position, tokenIndex, depth = position517, tokenIndex517, depth517
-												if buffer[position] != rune('S') {
-													goto l501
-												}
-												position++
-											}
-										l517:
-											{
-												position519, tokenIndex519, depth519 := position, tokenIndex, depth
-												if buffer[position] != rune('c') {
-													goto l520
-												}
-												position++
-												goto l519
-											l520:
-												position, tokenIndex, depth = position519, tokenIndex519, depth519
-												if buffer[position] != rune('C') {
-													goto l501
-												}
-												position++
-											}
-										l519:
-											if !rules[ruleskip]() {
-												goto l501
-											}
-											depth--
-											add(ruleDESC, position512)
+										if !rules[rulevar]() {
+											goto l579
 										}
+										goto l580
+									l579:
+										position, tokenIndex, depth = position579, tokenIndex579, depth579
 									}
-								l503:
-									goto l502
-								l501:
-									position, tokenIndex, depth = position501, tokenIndex501, depth501
-								}
-							l502:
-								if !rules[rulebrackettedExpression]() {
-									goto l500
-								}
-								goto l499
-							l500:
-								position, tokenIndex, depth = position499, tokenIndex499, depth499
-								if !rules[rulefunctionCall]() {
-									goto l521
-								}
-								goto l499
-							l521:
-								position, tokenIndex, depth = position499, tokenIndex499, depth499
-								if !rules[rulebuiltinCall]() {
-									goto l522
-								}
-								goto l499
-							l522:
-								position, tokenIndex, depth = position499, tokenIndex499, depth499
-								if !rules[rulevar]() {
-									goto l484
+								l580:
+									if !rules[ruleRPAREN]() {
+										goto l481
+									}
+									break
+								default:
+									if !rules[rulebuiltinCall]() {
+										goto l481
+									}
+									break
 								}
 							}
-						l499:
-							depth--
-							add(ruleorderCondition, position498)
+
 						}
-					l496:
+					l576:
+						depth--
+						add(rulegroupCondition, position575)
+					}
+				l573:
+					{
+						position574, tokenIndex574, depth574 := position, tokenIndex, depth
 						{
-							position497, tokenIndex497, depth497 := position, tokenIndex, depth
+							position581 := position
+							depth++
 							{
-								position523 := position
-								depth++
+								position582, tokenIndex582, depth582 := position, tokenIndex, depth
+								if !rules[rulefunctionCall]() {
+									goto l583
+								}
+								goto l582
+							l583:
+								position, tokenIndex, depth = position582, tokenIndex582, depth582
 								{
-									position524, tokenIndex524, depth524 := position, tokenIndex, depth
-									{
-										position526, tokenIndex526, depth526 := position, tokenIndex, depth
+									switch buffer[position] {
+									case '$', '?':
+										if !rules[rulevar]() {
+											goto l574
+										}
+										break
+									case '(':
+										if !rules[ruleLPAREN]() {
+											goto l574
+										}
+										if !rules[ruleexpression]() {
+											goto l574
+										}
 										{
-											position528, tokenIndex528, depth528 := position, tokenIndex, depth
-											{
-												position530 := position
-												depth++
-												{
-													position531, tokenIndex531, depth531 := position, tokenIndex, depth
-													if buffer[position] != rune('a') {
-														goto l532
-													}
-													position++
-													goto l531
-												l532:
-													position, tokenIndex, depth = position531, tokenIndex531, depth531
-													if buffer[position] != rune('A') {
-														goto l529
-													}
-													position++
-												}
-											l531:
-												{
-													position533, tokenIndex533, depth533 := position, tokenIndex, depth
-													if buffer[position] != rune('s') {
-														goto l534
-													}
-													position++
-													goto l533
-												l534:
-													position, tokenIndex, depth = position533, tokenIndex533, depth533
-													if buffer[position] != rune('S') {
-														goto l529
-													}
-													position++
-												}
-											l533:
-												{
-													position535, tokenIndex535, depth535 := position, tokenIndex, depth
-													if buffer[position] != rune('c') {
-														goto l536
-													}
-													position++
-													goto l535
-												l536:
-													position, tokenIndex, depth = position535, tokenIndex535, depth535
-													if buffer[position] != rune('C') {
-														goto l529
-													}
-													position++
-												}
-											l535:
-												if !rules[ruleskip]() {
-													goto l529
-												}
-												depth--
-												add(ruleASC, position530)
+											position585, tokenIndex585, depth585 := position, tokenIndex, depth
+											if !rules[ruleAS]() {
+												goto l585
 											}
-											goto l528
-										l529:
-											position, tokenIndex, depth = position528, tokenIndex528, depth528
-											{
-												position537 := position
-												depth++
-												{
-													position538, tokenIndex538, depth538 := position, tokenIndex, depth
-													if buffer[position] != rune('d') {
-														goto l539
-													}
-													position++
-													goto l538
-												l539:
-													position, tokenIndex, depth = position538, tokenIndex538, depth538
-													if buffer[position] != rune('D') {
-														goto l526
-													}
-													position++
-												}
-											l538:
-												{
-													position540, tokenIndex540, depth540 := position, tokenIndex, depth
-													if buffer[position] != rune('e') {
-														goto l541
-													}
-													position++
-													goto l540
-												l541:
-													position, tokenIndex, depth = position540, tokenIndex540, depth540
-													if buffer[position] != rune('E') {
-														goto l526
-													}
-													position++
-												}
-											l540:
-												{
-													position542, tokenIndex542, depth542 := position, tokenIndex, depth
-													if buffer[position] != rune('s') {
-														goto l543
-													}
-													position++
-													goto l542
-												l543:
-													position, tokenIndex, depth = position542, tokenIndex542, depth542
-													if buffer[position] != rune('S') {
-														goto l526
-													}
-													position++
-												}
-											l542:
-												{
-													position544, tokenIndex544, depth544 := position, tokenIndex, depth
-													if buffer[position] != rune('c') {
-														goto l545
-													}
-													position++
-													goto l544
-												l545:
-													position, tokenIndex, depth = position544, tokenIndex544, depth544
-													if buffer[position] != rune('C') {
-														goto l526
-													}
-													position++
-												}
-											l544:
-												if !rules[ruleskip]() {
-													goto l526
-												}
-												depth--
-												add(ruleDESC, position537)
+											if !rules[rulevar]() {
+												goto l585
 											}
+											goto l586
+										l585:
+											position, tokenIndex, depth = position585, tokenIndex585, depth585
 										}
-									l528:
-										goto l527
-									l526:
-										position, tokenIndex, depth = position526, tokenIndex526, depth526
-									}
-								l527:
-									if !rules[rulebrackettedExpression]() {
-										goto l525
-									}
-									goto l524
-								l525:
-									position, tokenIndex, depth = position524, tokenIndex524, depth524
-									if !rules[rulefunctionCall]() {
-										goto l546
-									}
-									goto l524
-								l546:
-									position, tokenIndex, depth = position524, tokenIndex524, depth524
-									if !rules[rulebuiltinCall]() {
-										goto l547
-									}
-									goto l524
-								l547:
-									position, tokenIndex, depth = position524, tokenIndex524, depth524
-									if !rules[rulevar]() {
-										goto l497
+									l586:
+										if !rules[ruleRPAREN]() {
+											goto l574
+										}
+										break
+									default:
+										if !rules[rulebuiltinCall]() {
+											goto l574
+										}
+										break
 									}
 								}
-							l524:
-								depth--
-								add(ruleorderCondition, position523)
+
+							}
+						l582:
+							depth--
+							add(rulegroupCondition, position581)
+						}
+						goto l573
+					l574:
+						position, tokenIndex, depth = position574, tokenIndex574, depth574
+					}
+					goto l482
+				l481:
+					position, tokenIndex, depth = position481, tokenIndex481, depth481
+				}
+			l482:
+				{
+					position483, tokenIndex483, depth483 := position, tokenIndex, depth
+					{
+						position549 := position
+						depth++
+						{
+							position550, tokenIndex550, depth550 := position, tokenIndex, depth
+							if buffer[position] != rune('h') {
+								goto l551
+							}
+							position++
+							goto l550
+						l551:
+							position, tokenIndex, depth = position550, tokenIndex550, depth550
+							if buffer[position] != rune('H') {
+								goto l483
+							}
+							position++
+						}
+					l550:
+						{
+							position552, tokenIndex552, depth552 := position, tokenIndex, depth
+							if buffer[position] != rune('a') {
+								goto l553
+							}
+							position++
+							goto l552
+						l553:
+							position, tokenIndex, depth = position552, tokenIndex552, depth552
+							if buffer[position] != rune('A') {
+								goto l483
+							}
+							position++
+						}
+					l552:
+						{
+							position554, tokenIndex554, depth554 := position, tokenIndex, depth
+							if buffer[position] != rune('v') {
+								goto l555
+							}
+							position++
+							goto l554
+						l555:
+							position, tokenIndex, depth = position554, tokenIndex554, depth554
+							if buffer[position] != rune('V') {
+								goto l483
+							}
+							position++
+						}
+					l554:
+						{
+							position556, tokenIndex556, depth556 := position, tokenIndex, depth
+							if buffer[position] != rune('i') {
+								goto l557
+							}
+							position++
+							goto l556
+						l557:
+							position, tokenIndex, depth = position556, tokenIndex556, depth556
+							if buffer[position] != rune('I') {
+								goto l483
+							}
+							position++
+						}
+					l556:
+						{
+							position558, tokenIndex558, depth558 := position, tokenIndex, depth
+							if buffer[position] != rune('n') {
+								goto l559
+							}
+							position++
+							goto l558
+						l559:
+							position, tokenIndex, depth = position558, tokenIndex558, depth558
+							if buffer[position] != rune('N') {
+								goto l483
+							}
+							position++
+						}
+					l558:
+						{
+							position560, tokenIndex560, depth560 := position, tokenIndex, depth
+							if buffer[position] != rune('g') {
+								goto l561
 							}
-							goto l496
-						l497:
-							position, tokenIndex, depth = position497, tokenIndex497, depth497
+							position++
+							goto l560
+						l561:
+							position, tokenIndex, depth = position560, tokenIndex560, depth560
+							if buffer[position] != rune('G') {
+								goto l483
+							}
+							position++
 						}
+					l560:
+						if !rules[ruleskip]() {
+							goto l483
+						}
+						depth--
+						add(ruleHAVING, position549)
+					}
+					if !rules[ruleconstraint]() {
 						goto l483
-					l484:
-						position, tokenIndex, depth = position483, tokenIndex483, depth483
+					}
+					goto l484
+				l483:
+					position, tokenIndex, depth = position483, tokenIndex483, depth483
+				}
+			l484:
+				{
+					position594, tokenIndex594, depth594 := position, tokenIndex, depth
+					{
+						position485 := position
+						depth++
 						{
-							switch buffer[position] {
-							case 'H', 'h':
-								{
-									position549 := position
-									depth++
-									{
-										position550, tokenIndex550, depth550 := position, tokenIndex, depth
-										if buffer[position] != rune('h') {
-											goto l551
-										}
-										position++
-										goto l550
-									l551:
-										position, tokenIndex, depth = position550, tokenIndex550, depth550
-										if buffer[position] != rune('H') {
-											goto l481
-										}
-										position++
-									}
-								l550:
-									{
-										position552, tokenIndex552, depth552 := position, tokenIndex, depth
-										if buffer[position] != rune('a') {
-											goto l553
-										}
-										position++
-										goto l552
-									l553:
-										position, tokenIndex, depth = position552, tokenIndex552, depth552
-										if buffer[position] != rune('A') {
-											goto l481
-										}
-										position++
-									}
-								l552:
-									{
-										position554, tokenIndex554, depth554 := position, tokenIndex, depth
-										if buffer[position] != rune('v') {
-											goto l555
-										}
-										position++
-										goto l554
-									l555:
-										position, tokenIndex, depth = position554, tokenIndex554, depth554
-										if buffer[position] != rune('V') {
-											goto l481
-										}
-										position++
-									}
-								l554:
-									{
-										position556, tokenIndex556, depth556 := position, tokenIndex, depth
-										if buffer[position] != rune('i') {
-											goto l557
-										}
-										position++
-										goto l556
-									l557:
-										position, tokenIndex, depth = position556, tokenIndex556, depth556
-										if buffer[position] != rune('I') {
-											goto l481
-										}
-										position++
-									}
-								l556:
-									{
-										position558, tokenIndex558, depth558 := position, tokenIndex, depth
-										if buffer[position] != rune('n') {
-											goto l559
-										}
-										position++
-										goto l558
-									l559:
-										position, tokenIndex, depth = position558, tokenIndex558, depth558
-										if buffer[position] != rune('N') {
-											goto l481
-										}
-										position++
-									}
-								l558:
-									{
-										position560, tokenIndex560, depth560 := position, tokenIndex, depth
-										if buffer[position] != rune('g') {
-											goto l561
-										}
-										position++
-										goto l560
-									l561:
-										position, tokenIndex, depth = position560, tokenIndex560, depth560
-										if buffer[position] != rune('G') {
-											goto l481
-										}
-										position++
-									}
-								l560:
-									if !rules[ruleskip]() {
-										goto l481
-									}
-									depth--
-									add(ruleHAVING, position549)
-								}
-								if !rules[ruleconstraint]() {
-									goto l481
-								}
-								break
-							case 'G', 'g':
+							position486, tokenIndex486, depth486 := position, tokenIndex, depth
+							if buffer[position] != rune('o') {
+								goto l487
+							}
+							position++
+							goto l486
+						l487:
+							position, tokenIndex, depth = position486, tokenIndex486, depth486
+							if buffer[position] != rune('O') {
+								goto l594
+							}
+							position++
+						}
+					l486:
+						{
+							position488, tokenIndex488, depth488 := position, tokenIndex, depth
+							if buffer[position] != rune('r') {
+								goto l489
+							}
+							position++
+							goto l488
+						l489:
+							position, tokenIndex, depth = position488, tokenIndex488, depth488
+							if buffer[position] != rune('R') {
+								goto l594
+							}
+							position++
+						}
+					l488:
+						{
+							position490, tokenIndex490, depth490 := position, tokenIndex, depth
+							if buffer[position] != rune('d') {
+								goto l491
+							}
+							position++
+							goto l490
+						l491:
+							position, tokenIndex, depth = position490, tokenIndex490, depth490
+							if buffer[position] != rune('D') {
+								goto l594
+							}
+							position++
+						}
+					l490:
+						{
+							position492, tokenIndex492, depth492 := position, tokenIndex, depth
+							if buffer[position] != rune('e') {
+								goto l493
+							}
+							position++
+							goto l492
+						l493:
+							position, tokenIndex, depth = position492, tokenIndex492, depth492
+							if buffer[position] != rune('E') {
+								goto l594
+							}
+							position++
+						}
+					l492:
+						{
+							position494, tokenIndex494, depth494 := position, tokenIndex, depth
+							if buffer[position] != rune('r') {
+								goto l495
+							}
+							position++
+							goto l494
+						l495:
+							position, tokenIndex, depth = position494, tokenIndex494, depth494
+							if buffer[position] != rune('R') {
+								goto l594
+							}
+							position++
+						}
+					l494:
+						if !rules[ruleskip]() {
+							goto l594
+						}
+						depth--
+						add(ruleORDER, position485)
+					}
+					if !rules[ruleBY]() {
+						goto l594
+					}
+					{
+						position498 := position
+						depth++
+						{
+							position499, tokenIndex499, depth499 := position, tokenIndex, depth
+							{
+								position501, tokenIndex501, depth501 := position, tokenIndex, depth
 								{
-									position562 := position
-									depth++
+									position503, tokenIndex503, depth503 := position, tokenIndex, depth
 									{
-										position563, tokenIndex563, depth563 := position, tokenIndex, depth
-										if buffer[position] != rune('g') {
-											goto l564
+										position505 := position
+										depth++
+										{
+											position506, tokenIndex506, depth506 := position, tokenIndex, depth
+											if buffer[position] != rune('a') {
+												goto l507
+											}
+											position++
+											goto l506
+										l507:
+											position, tokenIndex, depth = position506, tokenIndex506, depth506
+											if buffer[position] != rune('A') {
+												goto l504
+											}
+											position++
 										}
-										position++
-										goto l563
-									l564:
-										position, tokenIndex, depth = position563, tokenIndex563, depth563
-										if buffer[position] != rune('G') {
-											goto l481
+									l506:
+										{
+											position508, tokenIndex508, depth508 := position, tokenIndex, depth
+											if buffer[position] != rune('s') {
+												goto l509
+											}
+											position++
+											goto l508
+										l509:
+											position, tokenIndex, depth = position508, tokenIndex508, depth508
+											if buffer[position] != rune('S') {
+												goto l504
+											}
+											position++
 										}
-										position++
-									}
-								l563:
-									{
-										position565, tokenIndex565, depth565 := position, tokenIndex, depth
-										if buffer[position] != rune('r') {
-											goto l566
+									l508:
+										{
+											position510, tokenIndex510, depth510 := position, tokenIndex, depth
+											if buffer[position] != rune('c') {
+												goto l511
+											}
+											position++
+											goto l510
+										l511:
+											position, tokenIndex, depth = position510, tokenIndex510, depth510
+											if buffer[position] != rune('C') {
+												goto l504
+											}
+											position++
 										}
-										position++
-										goto l565
-									l566:
-										position, tokenIndex, depth = position565, tokenIndex565, depth565
-										if buffer[position] != rune('R') {
-											goto l481
+									l510:
+										if !rules[ruleskip]() {
+											goto l504
 										}
-										position++
+										depth--
+										add(ruleASC, position505)
 									}
-								l565:
+									goto l503
+								l504:
+									position, tokenIndex, depth = position503, tokenIndex503, depth503
 									{
-										position567, tokenIndex567, depth567 := position, tokenIndex, depth
-										if buffer[position] != rune('o') {
-											goto l568
-										}
-										position++
-										goto l567
-									l568:
-										position, tokenIndex, depth = position567, tokenIndex567, depth567
-										if buffer[position] != rune('O') {
-											goto l481
+										position512 := position
+										depth++
+										{
+											position513, tokenIndex513, depth513 := position, tokenIndex, depth
+											if buffer[position] != rune('d') {
+												goto l514
+											}
+											position++
+											goto l513
+										l514:
+											position, tokenIndex, depth = position513, tokenIndex513, depth513
+											if buffer[position] != rune('D') {
+												goto l501
+											}
+											position++
 										}
-										position++
-									}
-								l567:
-									{
-										position569, tokenIndex569, depth569 := position, tokenIndex, depth
-										if buffer[position] != rune('u') {
-											goto l570
+									l513:
+										{
+											position515, tokenIndex515, depth515 := position, tokenIndex, depth
+											if buffer[position] != rune('e') {
+												goto l516
+											}
+											position++
+											goto l515
+										l516:
+											position, tokenIndex, depth = position515, tokenIndex515, depth515
+											if buffer[position] != rune('E') {
+												goto l501
+											}
+											position++
 										}
-										position++
-										goto l569
-									l570:
-										position, tokenIndex, depth = position569, tokenIndex569, depth569
-										if buffer[position] != rune('U') {
-											goto l481
+									l515:
+										{
+											position517, tokenIndex517, depth517 := position, tokenIndex, depth
+											if buffer[position] != rune('s') {
+												goto l518
+											}
+											position++
+											goto l517
+										l518:
+											position, tokenIndex, depth = position517, tokenIndex517, depth517
+											if buffer[position] != rune('S') {
+												goto l501
+											}
+											position++
 										}
-										position++
-									}
-								l569:
-									{
-										position571, tokenIndex571, depth571 := position, tokenIndex, depth
-										if buffer[position] != rune('p') {
-											goto l572
+									l517:
+										{
+											position519, tokenIndex519, depth519 := position, tokenIndex, depth
+											if buffer[position] != rune('c') {
+												goto l520
+											}
+											position++
+											goto l519
+										l520:
+											position, tokenIndex, depth = position519, tokenIndex519, depth519
+											if buffer[position] != rune('C') {
+												goto l501
+											}
+											position++
 										}
-										position++
-										goto l571
-									l572:
-										position, tokenIndex, depth = position571, tokenIndex571, depth571
-										if buffer[position] != rune('P') {
-											goto l481
+									l519:
+										if !rules[ruleskip]() {
+											goto l501
 										}
-										position++
-									}
-								l571:
-									if !rules[ruleskip]() {
-										goto l481
+										depth--
+										add(ruleDESC, position512)
 									}
-									depth--
-									add(ruleGROUP, position562)
-								}
-								if !rules[ruleBY]() {
-									goto l481
 								}
+							l503:
+								goto l502
+							l501:
+								position, tokenIndex, depth = position501, tokenIndex501, depth501
+							}
+						l502:
+							if !rules[rulebrackettedExpression]() {
+								goto l500
+							}
+							goto l499
+						l500:
+							position, tokenIndex, depth = position499, tokenIndex499, depth499
+							if !rules[rulefunctionCall]() {
+								goto l521
+							}
+							goto l499
+						l521:
+							position, tokenIndex, depth = position499, tokenIndex499, depth499
+							if !rules[rulebuiltinCall]() {
+								goto l522
+							}
+							goto l499
+						l522:
+							position, tokenIndex, depth = position499, tokenIndex499, depth499
+							if !rules[rulevar]() {
+								goto l594
+							}
+						}
+					l499:
+						depth--
+						add(ruleorderCondition, position498)
+					}
+				l496:
+					{
+						position497, tokenIndex497, depth497 := position, tokenIndex, depth
+						{
+							position523 := position
+							depth++
+							{
+								position524, tokenIndex524, depth524 := position, tokenIndex, depth
 								{
-									position575 := position
-									depth++
+									position526, tokenIndex526, depth526 := position, tokenIndex, depth
 									{
-										position576, tokenIndex576, depth576 := position, tokenIndex, depth
-										if !rules[rulefunctionCall]() {
-											goto l577
-										}
-										goto l576
-									l577:
-										position, tokenIndex, depth = position576, tokenIndex576, depth576
+										position528, tokenIndex528, depth528 := position, tokenIndex, depth
 										{
-											switch buffer[position] {
-											case '$', '?':
-												if !rules[rulevar]() {
-													goto l481
+											position530 := position
+											depth++
+											{
+												position531, tokenIndex531, depth531 := position, tokenIndex, depth
+												if buffer[position] != rune('a') {
+													goto l532
 												}
-												break
-											case '(':
-												if !rules[ruleLPAREN]() {
-													goto l481
+												position++
+												goto l531
+											l532:
+												position, tokenIndex, depth = position531, tokenIndex531, depth531
+												if buffer[position] != rune('A') {
+													goto l529
 												}
-												if !rules[ruleexpression]() {
-													goto l481
+												position++
+											}
+										l531:
+											{
+												position533, tokenIndex533, depth533 := position, tokenIndex, depth
+												if buffer[position] != rune('s') {
+													goto l534
 												}
-												{
-													position579, tokenIndex579, depth579 := position, tokenIndex, depth
-													if !rules[ruleAS]() {
-														goto l579
-													}
-													if !rules[rulevar]() {
-														goto l579
-													}
-													goto l580
-												l579:
-													position, tokenIndex, depth = position579, tokenIndex579, depth579
+												position++
+												goto l533
+											l534:
+												position, tokenIndex, depth = position533, tokenIndex533, depth533
+												if buffer[position] != rune('S') {
+													goto l529
 												}
-											l580:
-												if !rules[ruleRPAREN]() {
-													goto l481
+												position++
+											}
+										l533:
+											{
+												position535, tokenIndex535, depth535 := position, tokenIndex, depth
+												if buffer[position] != rune('c') {
+													goto l536
 												}
-												break
-											default:
-												if !rules[rulebuiltinCall]() {
-													goto l481
+												position++
+												goto l535
+											l536:
+												position, tokenIndex, depth = position535, tokenIndex535, depth535
+												if buffer[position] != rune('C') {
+													goto l529
 												}
-												break
+												position++
 											}
+										l535:
+											if !rules[ruleskip]() {
+												goto l529
+											}
+											depth--
+											add(ruleASC, position530)
 										}
-
-									}
-								l576:
-									depth--
-									add(rulegroupCondition, position575)
-								}
-							l573:
-								{
-									position574, tokenIndex574, depth574 := position, tokenIndex, depth
-									{
-										position581 := position
-										depth++
+										goto l528
+									l529:
+										position, tokenIndex, depth = position528, tokenIndex528, depth528
 										{
-											position582, tokenIndex582, depth582 := position, tokenIndex, depth
-											if !rules[rulefunctionCall]() {
-												goto l583
+											position537 := position
+											depth++
+											{
+												position538, tokenIndex538, depth538 := position, tokenIndex, depth
+												if buffer[position] != rune('d') {
+													goto l539
+												}
+												position++
+												goto l538
+											l539:
+												position, tokenIndex, depth = position538, tokenIndex538, depth538
+												if buffer[position] != rune('D') {
+													goto l526
+												}
+												position++
 											}
-											goto l582
-										l583:
-											position, tokenIndex, depth = position582, tokenIndex582, depth582
+										l538:
 											{
-												switch buffer[position] {
-												case '$', '?':
-													if !rules[rulevar]() {
-														goto l574
-													}
-													break
-												case '(':
-													if !rules[ruleLPAREN]() {
-														goto l574
-													}
-													if !rules[ruleexpression]() {
-														goto l574
-													}
-													{
-														position585, tokenIndex585, depth585 := position, tokenIndex, depth
-														if !rules[ruleAS]() {
-															goto l585
-														}
-														if !rules[rulevar]() {
-															goto l585
-														}
-														goto l586
-													l585:
-														position, tokenIndex, depth = position585, tokenIndex585, depth585
-													}
-												l586:
-													if !rules[ruleRPAREN]() {
-														goto l574
-													}
-													break
-												default:
-													if !rules[rulebuiltinCall]() {
-														goto l574
-													}
-													break
+												position540, tokenIndex540, depth540 := position, tokenIndex, depth
+												if buffer[position] != rune('e') {
+													goto l541
+												}
+												position++
+												goto l540
+											l541:
+												position, tokenIndex, depth = position540, tokenIndex540, depth540
+												if buffer[position] != rune('E') {
+													goto l526
 												}
+												position++
 											}
-
-										}
-									l582:
-										depth--
-										add(rulegroupCondition, position581)
-									}
-									goto l573
-								l574:
-									position, tokenIndex, depth = position574, tokenIndex574, depth574
-								}
-								break
-							default:
-								{
-									position587 := position
-									depth++
-									{
-										position588, tokenIndex588, depth588 := position, tokenIndex, depth
-										if !rules[rulelimit]() {
-											goto l589
-										}
-										{
-											position590, tokenIndex590, depth590 := position, tokenIndex, depth
-											if !rules[ruleoffset]() {
-												goto l590
+										l540:
+											{
+												position542, tokenIndex542, depth542 := position, tokenIndex, depth
+												if buffer[position] != rune('s') {
+													goto l543
+												}
+												position++
+												goto l542
+											l543:
+												position, tokenIndex, depth = position542, tokenIndex542, depth542
+												if buffer[position] != rune('S') {
+													goto l526
+												}
+												position++
 											}
-											goto l591
-										l590:
-											position, tokenIndex, depth = position590, tokenIndex590, depth590
-										}
-									l591:
-										goto l588
-									l589:
-										position, tokenIndex, depth = position588, tokenIndex588, depth588
-										if !rules[ruleoffset]() {
-											goto l481
-										}
-										{
-											position592, tokenIndex592, depth592 := position, tokenIndex, depth
-											if !rules[rulelimit]() {
-												goto l592
+										l542:
+											{
+												position544, tokenIndex544, depth544 := position, tokenIndex, depth
+												if buffer[position] != rune('c') {
+													goto l545
+												}
+												position++
+												goto l544
+											l545:
+												position, tokenIndex, depth = position544, tokenIndex544, depth544
+												if buffer[position] != rune('C') {
+													goto l526
+												}
+												position++
+											}
+										l544:
+											if !rules[ruleskip]() {
+												goto l526
 											}
-											goto l593
-										l592:
-											position, tokenIndex, depth = position592, tokenIndex592, depth592
+											depth--
+											add(ruleDESC, position537)
 										}
-									l593:
 									}
-								l588:
-									depth--
-									add(rulelimitOffsetClauses, position587)
+								l528:
+									goto l527
+								l526:
+									position, tokenIndex, depth = position526, tokenIndex526, depth526
+								}
+							l527:
+								if !rules[rulebrackettedExpression]() {
+									goto l525
+								}
+								goto l524
+							l525:
+								position, tokenIndex, depth = position524, tokenIndex524, depth524
+								if !rules[rulefunctionCall]() {
+									goto l546
+								}
+								goto l524
+							l546:
+								position, tokenIndex, depth = position524, tokenIndex524, depth524
+								if !rules[rulebuiltinCall]() {
+									goto l547
+								}
+								goto l524
+							l547:
+								position, tokenIndex, depth = position524, tokenIndex524, depth524
+								if !rules[rulevar]() {
+									goto l497
 								}
-								break
 							}
+						l524:
+							depth--
+							add(ruleorderCondition, position523)
 						}
-
+						goto l496
+					l497:
+						position, tokenIndex, depth = position497, tokenIndex497, depth497
 					}
-				l483:
-					goto l482
-				l481:
-					position, tokenIndex, depth = position481, tokenIndex481, depth481
+					goto l595
+				l594:
+					position, tokenIndex, depth = position594, tokenIndex594, depth594
 				}
-			l482:
+			l595:
+				{
+					position596, tokenIndex596, depth596 := position, tokenIndex, depth
+					{
+						position587 := position
+						depth++
+						{
+							position588, tokenIndex588, depth588 := position, tokenIndex, depth
+							if !rules[rulelimit]() {
+								goto l589
+							}
+							{
+								position590, tokenIndex590, depth590 := position, tokenIndex, depth
+								if !rules[ruleoffset]() {
+									goto l590
+								}
+								goto l591
+							l590:
+								position, tokenIndex, depth = position590, tokenIndex590, depth590
+							}
+						l591:
+							goto l588
+						l589:
+							position, tokenIndex, depth = position588, tokenIndex588, depth588
+							if !rules[ruleoffset]() {
+								goto l596
+							}
+							{
+								position592, tokenIndex592, depth592 := position, tokenIndex, depth
+								if !rules[rulelimit]() {
+									goto l592
+								}
+								goto l593
+							l592:
+								position, tokenIndex, depth = position592, tokenIndex592, depth592
+							}
+						l593:
+						}
+					l588:
+						depth--
+						add(rulelimitOffsetClauses, position587)
+					}
+					goto l597
+				l596:
+					position, tokenIndex, depth = position596, tokenIndex596, depth596
+				}
+			l597:
 				depth--
 				add(rulesolutionModifier, position480)
 			}
@@ -12739,219 +12858,228 @@ func (p *SparqlGraph) Init() {
 						position1560 := position
 						depth++
 						{
-							position1561, tokenIndex1561, depth1561 := position, tokenIndex, depth
-							if !rules[rulepnPrefix]() {
-								goto l1561
-							}
-							goto l1562
-						l1561:
-							position, tokenIndex, depth = position1561, tokenIndex1561, depth1561
-						}
-					l1562:
-						if buffer[position] != rune(':') {
-							goto l1556
-						}
-						position++
-						{
-							position1563 := position
+							position1911 := position
 							depth++
-						l1564:
 							{
-								position1565, tokenIndex1565, depth1565 := position, tokenIndex, depth
+								position1561, tokenIndex1561, depth1561 := position, tokenIndex, depth
+								if !rules[rulepnPrefix]() {
+									goto l1561
+								}
+								goto l1562
+							l1561:
+								position, tokenIndex, depth = position1561, tokenIndex1561, depth1561
+							}
+						l1562:
+							if buffer[position] != rune(':') {
+								goto l1556
+							}
+							position++
+							{
+								position1563 := position
+								depth++
+							l1564:
 								{
-									switch buffer[position] {
-									case '%', '\\':
-										{
-											position1567 := position
-											depth++
+									position1565, tokenIndex1565, depth1565 := position, tokenIndex, depth
+									{
+										switch buffer[position] {
+										case '%', '\\':
 											{
-												position1568, tokenIndex1568, depth1568 := position, tokenIndex, depth
-												{
-													position1570 := position
-													depth++
-													if buffer[position] != rune('%') {
-														goto l1569
-													}
-													position++
-													if !rules[rulehex]() {
-														goto l1569
-													}
-													if !rules[rulehex]() {
-														goto l1569
-													}
-													depth--
-													add(rulepercent, position1570)
-												}
-												goto l1568
-											l1569:
-												position, tokenIndex, depth = position1568, tokenIndex1568, depth1568
+												position1567 := position
+												depth++
 												{
-													position1571 := position
-													depth++
-													if buffer[position] != rune('\\') {
-														goto l1565
+													position1568, tokenIndex1568, depth1568 := position, tokenIndex, depth
+													{
+														position1570 := position
+														depth++
+														if buffer[position] != rune('%') {
+															goto l1569
+														}
+														position++
+														if !rules[rulehex]() {
+															goto l1569
+														}
+														if !rules[rulehex]() {
+															goto l1569
+														}
+														depth--
+														add(rulepercent, position1570)
 													}
-													position++
+													goto l1568
+												l1569:
+													position, tokenIndex, depth = position1568, tokenIndex1568, depth1568
 													{
-														switch buffer[position] {
-														case '%':
-															if buffer[position] != rune('%') {
-																goto l1565
-															}
-															position++
-															break
-														case '@':
-															if buffer[position] != rune('@') {
-																goto l1565
-															}
-															position++
-															break
-														case '#':
-															if buffer[position] != rune('#') {
-																goto l1565
-															}
-															position++
-															break
-														case '?':
-															if buffer[position] != rune('?') {
-																goto l1565
-															}
-															position++
-															break
-														case '/':
-															if buffer[position] != rune('/') {
-																goto l1565
-															}
-															position++
-															break
-														case '=':
-															if buffer[position] != rune('=') {
-																goto l1565
-															}
-															position++
-															break
-														case ';':
-															if buffer[position] != rune(';') {
-																goto l1565
-															}
-															position++
-															break
-														case ',':
-															if buffer[position] != rune(',') {
-																goto l1565
-															}
-															position++
-															break
-														case '+':
-															if buffer[position] != rune('+') {
-																goto l1565
-															}
-															position++
-															break
-														case '*':
-															if buffer[position] != rune('*') {
-																goto l1565
-															}
-															position++
-															break
-														case ')':
-															if buffer[position] != rune(')') {
-																goto l1565
-															}
-															position++
-															break
-														case '(':
-															if buffer[position] != rune('(') {
-																goto l1565
-															}
-															position++
-															break
-														case '\'':
-															if buffer[position] != rune('\'') {
-																goto l1565
-															}
-															position++
-															break
-														case '&':
-															if buffer[position] != rune('&') {
-																goto l1565
-															}
-															position++
-															break
-														case '$':
-															if buffer[position] != rune('$') {
-																goto l1565
-															}
-															position++
-															break
-														case '!':
-															if buffer[position] != rune('!') {
-																goto l1565
-															}
-															position++
-															break
-														case '-':
-															if buffer[position] != rune('-') {
-																goto l1565
-															}
-															position++
-															break
-														case '.':
-															if buffer[position] != rune('.') {
-																goto l1565
-															}
-															position++
-															break
-														case '~':
-															if buffer[position] != rune('~') {
-																goto l1565
-															}
-															position++
-															break
-														default:
-															if buffer[position] != rune('_') {
-																goto l1565
+														position1571 := position
+														depth++
+														if buffer[position] != rune('\\') {
+															goto l1565
+														}
+														position++
+														{
+															switch buffer[position] {
+															case '%':
+																if buffer[position] != rune('%') {
+																	goto l1565
+																}
+																position++
+																break
+															case '@':
+																if buffer[position] != rune('@') {
+																	goto l1565
+																}
+																position++
+																break
+															case '#':
+																if buffer[position] != rune('#') {
+																	goto l1565
+																}
+																position++
+																break
+															case '?':
+																if buffer[position] != rune('?') {
+																	goto l1565
+																}
+																position++
+																break
+															case '/':
+																if buffer[position] != rune('/') {
+																	goto l1565
+																}
+																position++
+																break
+															case '=':
+																if buffer[position] != rune('=') {
+																	goto l1565
+																}
+																position++
+																break
+															case ';':
+																if buffer[position] != rune(';') {
+																	goto l1565
+																}
+																position++
+																break
+															case ',':
+																if buffer[position] != rune(',') {
+																	goto l1565
+																}
+																position++
+																break
+															case '+':
+																if buffer[position] != rune('+') {
+																	goto l1565
+																}
+																position++
+																break
+															case '*':
+																if buffer[position] != rune('*') {
+																	goto l1565
+																}
+																position++
+																break
+															case ')':
+																if buffer[position] != rune(')') {
+																	goto l1565
+																}
+																position++
+																break
+															case '(':
+																if buffer[position] != rune('(') {
+																	goto l1565
+																}
+																position++
+																break
+															case '\'':
+																if buffer[position] != rune('\'') {
+																	goto l1565
+																}
+																position++
+																break
+															case '&':
+																if buffer[position] != rune('&') {
+																	goto l1565
+																}
+																position++
+																break
+															case '$':
+																if buffer[position] != rune('$') {
+																	goto l1565
+																}
+																position++
+																break
+															case '!':
+																if buffer[position] != rune('!') {
+																	goto l1565
+																}
+																position++
+																break
+															case '-':
+																if buffer[position] != rune('-') {
+																	goto l1565
+																}
+																position++
+																break
+															case '.':
+																if buffer[position] != rune('.') {
+																	goto l1565
+																}
+																position++
+																break
+															case '~':
+																if buffer[position] != rune('~') {
+																	goto l1565
+																}
+																position++
+																break
+															default:
+																if buffer[position] != rune('_') {
+																	goto l1565
+																}
+																position++
+																break
 															}
-															position++
-															break
 														}
-													}
 
-													depth--
-													add(rulepnLocalEsc, position1571)
+														depth--
+														add(rulepnLocalEsc, position1571)
+													}
 												}
+											l1568:
+												depth--
+												add(ruleplx, position1567)
 											}
-										l1568:
-											depth--
-											add(ruleplx, position1567)
-										}
-										break
-									case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-										if c := buffer[position]; c < rune('0') || c > rune('9') {
-											goto l1565
-										}
-										position++
-										break
-									case ':':
-										if buffer[position] != rune(':') {
-											goto l1565
-										}
-										position++
-										break
-									default:
-										if !rules[rulepnCharsU]() {
-											goto l1565
+											break
+										case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+											if c := buffer[position]; c < rune('0') || c > rune('9') {
+												goto l1565
+											}
+											position++
+											break
+										case ':':
+											if buffer[position] != rune(':') {
+												goto l1565
+											}
+											position++
+											break
+										default:
+											if !rules[rulepnCharsU]() {
+												goto l1565
+											}
+											break
 										}
-										break
 									}
-								}
 
-								goto l1564
-							l1565:
-								position, tokenIndex, depth = position1565, tokenIndex1565, depth1565
+									goto l1564
+								l1565:
+									position, tokenIndex, depth = position1565, tokenIndex1565, depth1565
+								}
+								depth--
+								add(rulepnLocal, position1563)
 							}
 							depth--
-							add(rulepnLocal, position1563)
+							add(rulePegText, position1911)
+						}
+						{
+							add(ruleAction10, position)
 						}
 						if !rules[ruleskip]() {
 							goto l1556
@@ -13023,7 +13151,7 @@ func (p *SparqlGraph) Init() {
 			position, tokenIndex, depth = position1573, tokenIndex1573, depth1573
 			return false
 		},
-		/* 73 prefixedName <- <(pnPrefix? ':' pnLocal skip)> */
+		/* 73 prefixedName <- <(<(pnPrefix? ':' pnLocal)> Action10 skip)> */
 		nil,
 		/* 74 literal <- <(<(string (('@' ([a-z] / [A-Z])+ ('-' ((&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') [0-9]) | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z') [A-Z]) | (&('a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') [a-z]))+)*) / ('^' '^' iriref))?)> Action5 skip)> */
 		func() bool {
@@ -13628,71 +13756,80 @@ func (p *SparqlGraph) Init() {
 			position, tokenIndex, depth = position1649, tokenIndex1649, depth1649
 			return false
 		},
-		/* 81 numericLiteral <- <(('+' / '-')? [0-9]+ ('.' [0-9]*)? skip)> */
+		/* 81 numericLiteral <- <(<(('+' / '-')? [0-9]+ ('.' [0-9]*)?)> Action17 skip)> */
 		func() bool {
 			position1652, tokenIndex1652, depth1652 := position, tokenIndex, depth
 			{
 				position1653 := position
 				depth++
 				{
-					position1654, tokenIndex1654, depth1654 := position, tokenIndex, depth
+					position1913 := position
+					depth++
 					{
-						position1656, tokenIndex1656, depth1656 := position, tokenIndex, depth
-						if buffer[position] != rune('+') {
-							goto l1657
-						}
-						position++
-						goto l1656
-					l1657:
-						position, tokenIndex, depth = position1656, tokenIndex1656, depth1656
-						if buffer[position] != rune('-') {
-							goto l1654
+						position1654, tokenIndex1654, depth1654 := position, tokenIndex, depth
+						{
+							position1656, tokenIndex1656, depth1656 := position, tokenIndex, depth
+							if buffer[position] != rune('+') {
+								goto l1657
+							}
+							position++
+							goto l1656
+						l1657:
+							position, tokenIndex, depth = position1656, tokenIndex1656, depth1656
+							if buffer[position] != rune('-') {
+								goto l1654
+							}
+							position++
 						}
-						position++
+					l1656:
+						goto l1655
+					l1654:
+						position, tokenIndex, depth = position1654, tokenIndex1654, depth1654
 					}
-				l1656:
-					goto l1655
-				l1654:
-					position, tokenIndex, depth = position1654, tokenIndex1654, depth1654
-				}
-			l1655:
-				if c := buffer[position]; c < rune('0') || c > rune('9') {
-					goto l1652
-				}
-				position++
-			l1658:
-				{
-					position1659, tokenIndex1659, depth1659 := position, tokenIndex, depth
+				l1655:
 					if c := buffer[position]; c < rune('0') || c > rune('9') {
-						goto l1659
-					}
-					position++
-					goto l1658
-				l1659:
-					position, tokenIndex, depth = position1659, tokenIndex1659, depth1659
-				}
-				{
-					position1660, tokenIndex1660, depth1660 := position, tokenIndex, depth
-					if buffer[position] != rune('.') {
-						goto l1660
+						goto l1652
 					}
 					position++
-				l1662:
+				l1658:
 					{
-						position1663, tokenIndex1663, depth1663 := position, tokenIndex, depth
+						position1659, tokenIndex1659, depth1659 := position, tokenIndex, depth
 						if c := buffer[position]; c < rune('0') || c > rune('9') {
-							goto l1663
+							goto l1659
+						}
+						position++
+						goto l1658
+					l1659:
+						position, tokenIndex, depth = position1659, tokenIndex1659, depth1659
+					}
+					{
+						position1660, tokenIndex1660, depth1660 := position, tokenIndex, depth
+						if buffer[position] != rune('.') {
+							goto l1660
 						}
 						position++
-						goto l1662
-					l1663:
-						position, tokenIndex, depth = position1663, tokenIndex1663, depth1663
+					l1662:
+						{
+							position1663, tokenIndex1663, depth1663 := position, tokenIndex, depth
+							if c := buffer[position]; c < rune('0') || c > rune('9') {
+								goto l1663
+							}
+							position++
+							goto l1662
+						l1663:
+							position, tokenIndex, depth = position1663, tokenIndex1663, depth1663
+						}
+						goto l1661
+					l1660:
+						position, tokenIndex, depth = position1660, tokenIndex1660, depth1660
 					}
-					goto l1661
-				l1660:
-					position, tokenIndex, depth = position1660, tokenIndex1660, depth1660
+				l1661:
+					depth--
+					add(rulePegText, position1913)
+				}
+				{
+					add(ruleAction17, position)
 				}
-			l1661:
 				if !rules[ruleskip]() {
 					goto l1652
 				}
@@ -13706,7 +13843,7 @@ func (p *SparqlGraph) Init() {
 		},
 		/* 82 signedNumericLiteral <- <(('+' / '-') [0-9]+ ('.' [0-9]*)? skip)> */
 		nil,
-		/* 83 booleanLiteral <- <(TRUE / FALSE)> */
+		/* 83 booleanLiteral <- <((TRUE Action18) / (FALSE Action19))> */
 		func() bool {
 			position1665, tokenIndex1665, depth1665 := position, tokenIndex, depth
 			{
@@ -13783,6 +13920,9 @@ func (p *SparqlGraph) Init() {
 						depth--
 						add(ruleTRUE, position1669)
 					}
+					{
+						add(ruleAction18, position)
+					}
 					goto l1667
 				l1668:
 					position, tokenIndex, depth = position1667, tokenIndex1667, depth1667
@@ -13870,6 +14010,9 @@ func (p *SparqlGraph) Init() {
 						depth--
 						add(ruleFALSE, position1678)
 					}
+					{
+						add(ruleAction19, position)
+					}
 				}
 			l1667:
 				depth--
@@ -13882,11 +14025,11 @@ func (p *SparqlGraph) Init() {
 		},
 		/* 84 blankNode <- <(blankNodeLabel / anon)> */
 		nil,
-		/* 85 blankNodeLabel <- <('_' ':' (pnCharsU / [0-9]) (((pnCharsU / ((&('.') '.') | (&('-') '-') | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') [0-9])))* pnCharsU) / ([0-9] / '-'))? skip)> */
+		/* 85 blankNodeLabel <- <(<('_' ':' (pnCharsU / [0-9]) (((pnCharsU / ((&('.') '.') | (&('-') '-') | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') [0-9])))* pnCharsU) / ([0-9] / '-'))?)> Action20 skip)> */
 		nil,
-		/* 86 anon <- <('[' ws* ']' skip)> */
+		/* 86 anon <- <('[' ws* ']' Action21 skip)> */
 		nil,
-		/* 87 nil <- <('(' ws* ')' skip)> */
+		/* 87 nil <- <('(' ws* ')' Action22 skip)> */
 		func() bool {
 			position1692, tokenIndex1692, depth1692 := position, tokenIndex, depth
 			{
@@ -13910,6 +14053,9 @@ func (p *SparqlGraph) Init() {
 					goto l1692
 				}
 				position++
+				{
+					add(ruleAction22, position)
+				}
 				if !rules[ruleskip]() {
 					goto l1692
 				}
@@ -15125,13 +15271,45 @@ func (p *SparqlGraph) Init() {
 		nil,
 		/* 228 Action2 <- <{ p.o = p.label; p.addStatement(p.s, p.p, p.o) }> */
 		nil,
-		/* 229 Action3 <- <{ p.label = buffer[begin:end] }> */
+		/* 229 Action3 <- <{ p.label = variable(buffer[begin:end]) }> */
+		nil,
+		/* 230 Action4 <- <{ p.label = p.resolve(buffer[begin:end]) }> */
+		nil,
+		/* 231 Action5 <- <{ p.label = literal(buffer[begin:end], p.label.Value) }> */
+		nil,
+		/* 232 Action6 <- <{ p.label = rdfType }> */
+		nil,
+		/* 233 Action7 <- <{ p.prefix = buffer[begin:end] }> */
+		nil,
+		/* 234 Action8 <- <{ p.addPrefix(p.prefix, p.label) }> */
+		nil,
+		/* 235 Action9 <- <{ p.base = p.label.Value }> */
+		nil,
+		/* 236 Action10 <- <{ p.label = p.expandPrefixedName(buffer[begin:end]) }> */
+		nil,
+		/* 237 Action11 <- <{ p.s = p.label }> */
+		nil,
+		/* 238 Action12 <- <{ p.beginCollection() }> */
+		nil,
+		/* 239 Action13 <- <{ p.addCollectionItem(p.label) }> */
+		nil,
+		/* 240 Action14 <- <{ p.label = p.endCollection() }> */
+		nil,
+		/* 241 Action15 <- <{ p.beginBlankNodePropertyList() }> */
+		nil,
+		/* 242 Action16 <- <{ p.label = p.endBlankNodePropertyList() }> */
+		nil,
+		/* 243 Action17 <- <{ p.label = numeric(buffer[begin:end]) }> */
+		nil,
+		/* 244 Action18 <- <{ p.label = trueLiteral }> */
+		nil,
+		/* 245 Action19 <- <{ p.label = falseLiteral }> */
 		nil,
-		/* 230 Action4 <- <{ p.label = buffer[begin:end] }> */
+		/* 246 Action20 <- <{ p.label = p.blankNode(buffer[begin:end]) }> */
 		nil,
-		/* 231 Action5 <- <{ p.label = buffer[begin:end] }> */
+		/* 247 Action21 <- <{ p.label = p.blankNode("") }> */
 		nil,
-		/* 232 Action6 <- <{ p.label = "<http://www.w3.org/1999/02/22-rdf-syntax-ns#type>" }> */
+		/* 248 Action22 <- <{ p.label = rdfNil }> */
 		nil,
 	}
 	p.rules = rules
@@ -1,6 +1,7 @@
 package qparser
 
 import (
+    "errors"
     "testing"
     "strings"
     "sort"
@@ -17,6 +18,22 @@ func sortPatterns(data ConnectedComponents) {
     sort.Sort(data)
 }
 
+// bodies checks that the patterns of the components are rendered as their
+// body, and removes them for comparing the components with the expected ones
+func bodies(t *testing.T, actual ConnectedComponents) ConnectedComponents {
+    for i := range actual {
+        body := ""
+        for _, tp := range actual[i].Patterns {
+            body += "    " + tp.String() + "\n"
+        }
+        if body != actual[i].Body {
+            t.Errorf("Expected the patterns to be rendered as %v, but got %v", actual[i].Body, body)
+        }
+        actual[i].Patterns = nil
+    }
+    return actual
+}
+
 func assert(t *testing.T, query string, expected ConnectedComponents) {
     sg := &SparqlGraph{}
     Reset(sg, query)
@@ -24,7 +41,7 @@ func assert(t *testing.T, query string, expected ConnectedComponents) {
         t.Errorf("Failed to parse query\n%v", err)
     }
     sg.Execute()
-    actual := sg.ConnectedComponents()
+    actual := bodies(t, sg.ConnectedComponents())
     if len(actual) != len(expected) {
         t.Errorf("Expected %v, but got %v", len(expected), len(actual))
     }
@@ -58,12 +75,13 @@ func TestService(t *testing.T) {
     `
     expected := ConnectedComponents{
         ConnectedComponent{
-`    ?v0 <http://bio2rdf.org/ns/kegg#xSubstrate> ?v1 .
+            Body: `    ?v0 <http://bio2rdf.org/ns/kegg#xSubstrate> ?v1 .
     ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://bio2rdf.org/ns/kegg#Enzyme> .
     ?v2 <http://bio2rdf.org/ns/kegg#xEnzyme> ?v0 .
     ?v2 <http://bio2rdf.org/ns/kegg#equation> ?v3 .
 `,
-            []int{ 2, 2 },
+            Complexity: []int{ 2, 2 },
+            Context: []Operator{ ServiceOp },
         },
     }
     assert(t, q, expected)
@@ -78,8 +96,8 @@ func TestLiteral1(t *testing.T) {
     `
     expected := ConnectedComponents{
         ConnectedComponent{
-            "    ?v0 <http://xmlns.com/foaf/0.1/name> ?v1 .\n",
-            []int{ 1 },
+            Body: "    ?v0 <http://xmlns.com/foaf/0.1/name> ?v1 .\n",
+            Complexity: []int{ 1 },
         },
     }
     assert(t, q, expected)
@@ -94,8 +112,8 @@ func TestLiteral2(t *testing.T) {
     `
     expected := ConnectedComponents{
         ConnectedComponent{
-            "    ?v0 <http://www.w3.org/2000/01/rdf-schema#comment> ?v1 .\n",
-            []int{ 1 },
+            Body: "    ?v0 <http://www.w3.org/2000/01/rdf-schema#comment> ?v1 .\n",
+            Complexity: []int{ 1 },
         },
     }
     assert(t, q, expected)
@@ -118,9 +136,9 @@ func TestStar1(t *testing.T) {
     `
     expected := ConnectedComponents{
         ConnectedComponent{
-            "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> .\n" +
+            Body: "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> .\n" +
             "    ?v0 <name> ?v1 .\n",
-            []int{ 2 },
+            Complexity: []int{ 2 },
         },
     }
     assert(t, q, expected)
@@ -135,13 +153,13 @@ func TestStar2(t *testing.T) {
     `
     expected := ConnectedComponents{
         ConnectedComponent{
-            "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> .\n" +
+            Body: "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> .\n" +
             "    ?v0 <name> ?v1 .\n",
-            []int{ 2 },
+            Complexity: []int{ 2 },
         }, ConnectedComponent{
-            "    ?v2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> .\n" +
-            "    ?v2 <age> ?v3 .\n",
-            []int{ 2 },
+            Body: "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> .\n" +
+            "    ?v0 <age> ?v1 .\n",
+            Complexity: []int{ 2 },
         },
     }
     assert(t, q, expected)
@@ -158,13 +176,13 @@ func TestStar3(t *testing.T) {
     `
     expected := ConnectedComponents{
         ConnectedComponent{
-            "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> .\n" +
+            Body: "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> .\n" +
             "    ?v0 <name> ?v1 .\n",
-            []int{ 2 },
+            Complexity: []int{ 2 },
         }, ConnectedComponent{
-            "    ?v2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> .\n" +
-            "    ?v2 <age> ?v3 .\n",
-            []int{ 2 },
+            Body: "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> .\n" +
+            "    ?v0 <age> ?v1 .\n",
+            Complexity: []int{ 2 },
         },
     }
     assert(t, q, expected)
@@ -180,9 +198,9 @@ func TestFilter(t *testing.T) {
     `
     expected := ConnectedComponents{
         ConnectedComponent{
-            "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> .\n" +
+            Body: "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> .\n" +
             "    ?v0 <name> ?v1 .\n",
-            []int{ 2 },
+            Complexity: []int{ 2 },
         },
     }
     assert(t, q, expected)
@@ -197,12 +215,12 @@ func TestPath1(t *testing.T) {
     `
     expected := ConnectedComponents{
         ConnectedComponent{
-            "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> .\n" +
+            Body: "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> .\n" +
             "    ?v0 <name> ?v1 .\n" +
             "    ?v0 <knows> ?v2 .\n" +
             "    ?v2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> .\n" +
             "    ?v2 <age> ?v3 .\n",
-            []int{ 2, 3 },
+            Complexity: []int{ 2, 3 },
         },
     }
     assert(t, q, expected)
@@ -217,14 +235,306 @@ func TestPath2(t *testing.T) {
     `
     expected := ConnectedComponents{
         ConnectedComponent{
-            "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> .\n" +
+            Body: "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> .\n" +
             "    ?v0 <name> ?v1 .\n" +
             "    ?v2 <knows> ?v0 .\n" +
             "    ?v2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> .\n" +
             "    ?v2 <age> ?v3 .\n",
-            []int{ 2, 3 },
+            Complexity: []int{ 2, 3 },
         },
     }
     assert(t, q, expected)
 }
 
+
+func TestSolutionModifiers(t *testing.T) {
+    q := `
+    select ?s (count(?o) as ?cnt) {
+        ?s <knows> ?o .
+        ?o <name> ?n
+    }
+    GROUP BY ?s
+    HAVING (count(?o) > 2)
+    ORDER BY desc(?cnt) ?s
+    LIMIT 10
+    OFFSET 20
+    `
+    expected := ConnectedComponents{
+        ConnectedComponent{
+            Body: "    ?v0 <knows> ?v1 .\n" +
+            "    ?v1 <name> ?v2 .\n",
+            Complexity: []int{ 1, 1 },
+        },
+    }
+    assert(t, q, expected)
+}
+
+func TestParseErrorPosition(t *testing.T) {
+    sg := &SparqlGraph{}
+    Reset(sg, "select * {\n  ?s <p>\n}")
+    err := sg.Parse()
+    if err == nil {
+        t.Fatal("Expected a parse error")
+    }
+    line, symbol, rule, ok := ParseErrorPosition(err)
+    if !ok || line != 3 || symbol != 1 || rule != "iri" {
+        t.Errorf("Expected line 3 symbol 1 after iri, but got line %v symbol %v after %v", line, symbol, rule)
+    }
+    if _, _, _, ok := ParseErrorPosition(errors.New("not a parse error")); ok {
+        t.Error("Expected no position")
+    }
+}
+
+func TestBGPCount(t *testing.T) {
+    for q, expected := range map[string]int{
+        "select * { ?s <p> ?o }" : 1,
+        "select * { ?s <p> ?o . OPTIONAL { ?o <q> ?z } FILTER(?z > 1) }" : 2,
+        "select * { { ?s <p> ?o } UNION { ?s <q> ?o . ?o <r> ?z } }" : 2,
+        "construct { ?s <p> ?o } where { FILTER(true) }" : 0,
+    } {
+        sg := &SparqlGraph{}
+        Reset(sg, q)
+        if err := sg.Parse(); err != nil {
+            t.Fatalf("Failed to parse query\n%v", err)
+        }
+        if actual := sg.BGPCount(); actual != expected {
+            t.Errorf("Expected %v BGPs in %v, but got %v", expected, q, actual)
+        }
+    }
+}
+
+func TestQueryForm(t *testing.T) {
+    for q, expected := range map[string]string{
+        "PREFIX s: <http://example.org/select#> select * { ?s <p> ?o }" : "select",
+        "construct { ?s <p> ?o } where { ?s <p> ?o }" : "construct",
+        "describe <a>" : "describe",
+        "ask { ?s <p> ?o . { select ?o { ?o <q> ?z } } }" : "ask",
+    } {
+        sg := &SparqlGraph{}
+        Reset(sg, q)
+        if err := sg.Parse(); err != nil {
+            t.Fatalf("Failed to parse query\n%v", err)
+        }
+        if actual := sg.QueryForm(); actual != expected {
+            t.Errorf("Expected the %v form for %v, but got %v", expected, q, actual)
+        }
+    }
+}
+
+func TestPrefixedNames(t *testing.T) {
+    q := `
+    BASE <http://example.org/data/>
+    PREFIX foaf: <http://xmlns.com/foaf/0.1/>
+    PREFIX : <vocab#>
+    SELECT * WHERE {
+        ?s foaf:name ?n ; :knows ?o .
+        ?o <friend> ?z ; dbo:birthPlace ?p ; rdf:type dbo:Person .
+    }
+    `
+    expected := ConnectedComponents{
+        {
+            Body: "    ?v0 <http://example.org/data/vocab#knows> ?v2 .\n" +
+                  "    ?v0 <http://xmlns.com/foaf/0.1/name> ?v1 .\n" +
+                  "    ?v2 <http://dbpedia.org/ontology/birthPlace> ?v4 .\n" +
+                  "    ?v2 <http://example.org/data/friend> ?v3 .\n" +
+                  "    ?v2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://dbpedia.org/ontology/Person> .\n",
+            Complexity: []int{ 2, 3 },
+        },
+    }
+    sg := &SparqlGraph{ Prefixes : DBpediaPrefixes }
+    Reset(sg, q)
+    if err := sg.Parse(); err != nil {
+        t.Fatalf("Failed to parse query\n%v", err)
+    }
+    sg.Execute()
+    if actual := bodies(t, sg.ConnectedComponents()); !reflect.DeepEqual(expected, actual) {
+        t.Errorf("Expected %v, but got %v", expected, actual)
+    }
+}
+
+func TestLiteralTerms(t *testing.T) {
+    q := `
+    SELECT * WHERE {
+        ?s <p> 12 ; <q> true .
+        ?z <r> 12 .
+        ?o <t> false ; <u> -1.5 .
+    }
+    `
+    expected := ConnectedComponents{
+        {
+            Body: "    ?v0 <p> ?v1 .\n" +
+                  "    ?v0 <q> ?v2 .\n" +
+                  "    ?v3 <r> ?v1 .\n",
+            Complexity: []int{ 1, 2 },
+        },
+        {
+            Body: "    ?v0 <t> ?v1 .\n" +
+                  "    ?v0 <u> ?v2 .\n",
+            Complexity: []int{ 2 },
+        },
+    }
+    assert(t, q, expected)
+}
+
+func TestTerms(t *testing.T) {
+    terms := map[string]Term{
+        `<http://xmlns.com/foaf/0.1/name>` : { Kind : IRI, Value : "http://xmlns.com/foaf/0.1/name" },
+        `?name` : { Kind : Variable, Value : "name" },
+        `_:b0` : { Kind : BlankNode, Value : "b0" },
+        `"chat"@fr` : { Kind : Literal, Value : "chat", Lang : "fr" },
+        `"say \"hi\"\n"` : { Kind : Literal, Value : "say \"hi\"\n" },
+        `"12"^^<http://www.w3.org/2001/XMLSchema#integer>` : { Kind : Literal, Value : "12", Datatype : xsdInteger },
+    }
+    for expected, term := range terms {
+        if actual := term.String(); actual != expected {
+            t.Errorf("Expected %v, but got %v", expected, actual)
+        }
+    }
+
+    sg := &SparqlGraph{}
+    Reset(sg, "select * { ?s a <:Person> ; <knows> ?o }")
+    if err := sg.Parse(); err != nil {
+        t.Fatalf("Failed to parse query\n%v", err)
+    }
+    sg.Execute()
+    expected := []TriplePattern{
+        { Term{ Kind : Variable, Value : "v0" }, rdfType, Term{ Kind : IRI, Value : ":Person" } },
+        { Term{ Kind : Variable, Value : "v0" }, Term{ Kind : IRI, Value : "knows" }, Term{ Kind : Variable, Value : "v1" } },
+    }
+    ccs := sg.ConnectedComponents()
+    if len(ccs) != 1 || !reflect.DeepEqual(expected, ccs[0].Patterns) {
+        t.Errorf("Expected %v, but got %v", expected, ccs)
+    }
+}
+
+func TestBlankNodes(t *testing.T) {
+    q := `
+    SELECT * WHERE {
+        ?s <p> _:a .
+        _:a <q> ?o .
+        ?x <p> [] .
+        ?y <p> [ <q> ?z ; <r> [ <t> ?w ] ] ; <u> ?v .
+    }
+    `
+    expected := ConnectedComponents{
+        {
+            Body: "    ?v0 <p> ?v1 .\n" +
+                  "    ?v1 <q> ?v2 .\n",
+            Complexity: []int{ 1, 1 },
+        },
+        {
+            Body: "    ?v0 <p> ?v1 .\n",
+            Complexity: []int{ 1 },
+        },
+        {
+            Body: "    ?v0 <q> ?v1 .\n" +
+                  "    ?v0 <r> ?v2 .\n" +
+                  "    ?v2 <t> ?v3 .\n" +
+                  "    ?v4 <p> ?v0 .\n" +
+                  "    ?v4 <u> ?v5 .\n",
+            Complexity: []int{ 1, 2, 2 },
+        },
+    }
+    assert(t, q, expected)
+}
+
+func TestCollections(t *testing.T) {
+    q := `
+    SELECT * WHERE {
+        ?s <p> ( ?a [ <q> ?b ] ) .
+    }
+    `
+    expected := ConnectedComponents{
+        {
+            Body: "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> ?v1 .\n" +
+                  "    ?v0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> ?v4 .\n" +
+                  "    ?v2 <q> ?v3 .\n" +
+                  "    ?v4 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> ?v2 .\n" +
+                  "    ?v4 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> ?v5 .\n" +
+                  "    ?v6 <p> ?v0 .\n",
+            Complexity: []int{ 1, 1, 2, 2 },
+        },
+    }
+    assert(t, q, expected)
+}
+
+func TestScopes(t *testing.T) {
+    q := `
+    SELECT * WHERE {
+        ?s <p> ?o .
+        { ?o <q> ?x }
+        { ?s <r> ?y . ?y <r> ?z } UNION { ?s <t> ?y . ?y <t> ?z }
+        OPTIONAL { ?o <u> ?w . GRAPH ?g { ?w <v> ?a ; <v> ?b } }
+        MINUS { ?s <w> ?c }
+        FILTER NOT EXISTS { ?s <x> ?d . ?d <x> ?e }
+        { SELECT ?s { ?s <y> ?f ; <z> ?h } }
+    }
+    `
+    expected := ConnectedComponents{
+        {
+            Body: "    ?v0 <p> ?v1 .\n" +
+                  "    ?v1 <q> ?v2 .\n",
+            Complexity: []int{ 1, 1 },
+        },
+        {
+            Body: "    ?v0 <r> ?v1 .\n" +
+                  "    ?v1 <r> ?v2 .\n",
+            Complexity: []int{ 1, 1 },
+            Context: []Operator{ UnionOp },
+        },
+        {
+            Body: "    ?v0 <t> ?v1 .\n" +
+                  "    ?v1 <t> ?v2 .\n",
+            Complexity: []int{ 1, 1 },
+            Context: []Operator{ UnionOp },
+        },
+        {
+            Body: "    ?v0 <u> ?v1 .\n",
+            Complexity: []int{ 1 },
+            Context: []Operator{ OptionalOp },
+        },
+        {
+            Body: "    ?v0 <v> ?v1 .\n" +
+                  "    ?v0 <v> ?v2 .\n",
+            Complexity: []int{ 2 },
+            Context: []Operator{ OptionalOp, GraphOp },
+        },
+        {
+            Body: "    ?v0 <w> ?v1 .\n",
+            Complexity: []int{ 1 },
+            Context: []Operator{ MinusOp },
+        },
+        {
+            Body: "    ?v0 <x> ?v1 .\n" +
+                  "    ?v1 <x> ?v2 .\n",
+            Complexity: []int{ 1, 1 },
+            Context: []Operator{ ExistsOp },
+        },
+        {
+            Body: "    ?v0 <y> ?v1 .\n" +
+                  "    ?v0 <z> ?v2 .\n",
+            Complexity: []int{ 2 },
+            Context: []Operator{ SubQueryOp },
+        },
+    }
+    assert(t, q, expected)
+}
+
+func TestScopeRenumbering(t *testing.T) {
+    q := `
+    SELECT * WHERE {
+        ?a <x> ?b . ?b <y> ?c
+        OPTIONAL { ?d <x> ?e . ?e <y> ?f }
+        { ?g <x> ?h } UNION { ?i <x> ?j . ?j <y> ?k }
+    }
+    `
+    body := "    ?v0 <x> ?v1 .\n" +
+            "    ?v1 <y> ?v2 .\n"
+    expected := ConnectedComponents{
+        { Body: body, Complexity: []int{ 1, 1 } },
+        { Body: body, Complexity: []int{ 1, 1 }, Context: []Operator{ OptionalOp } },
+        { Body: "    ?v0 <x> ?v1 .\n", Complexity: []int{ 1 }, Context: []Operator{ UnionOp } },
+        { Body: body, Complexity: []int{ 1, 1 }, Context: []Operator{ UnionOp } },
+    }
+    assert(t, q, expected)
+}
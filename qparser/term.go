@@ -0,0 +1,170 @@
+package qparser
+
+import (
+    "strings"
+)
+
+// TermKind is the kind of an RDF term
+type TermKind int
+
+const (
+    // Invalid is the kind of the zero Term, which is no RDF term
+    Invalid TermKind = iota
+    // IRI is an IRI, e.g., <http://xmlns.com/foaf/0.1/name>
+    IRI
+    // Literal is a literal, with a language tag or a datatype, e.g., "chat"@fr
+    Literal
+    // Variable is a query variable, e.g., ?name
+    Variable
+    // BlankNode is a blank node, e.g., _:b0
+    BlankNode
+)
+
+func (k TermKind) String() string {
+    switch k {
+    case Invalid:
+        return "invalid"
+    case IRI:
+        return "iri"
+    case Literal:
+        return "literal"
+    case Variable:
+        return "variable"
+    case BlankNode:
+        return "blank node"
+    }
+    return "unknown"
+}
+
+// Term is an RDF term of a triple pattern
+type Term struct {
+    Kind TermKind
+    // Value is the IRI, the lexical form of the literal, the name of the
+    // variable without the question mark, or the label of the blank node.
+    // An IRI whose prefix is not declared is kept as the prefixed name.
+    Value string
+    // Lang is the language tag of a literal
+    Lang string
+    // Datatype is the IRI of the datatype of a literal, empty for a simple
+    // literal or a literal with a language tag
+    Datatype string
+}
+
+// The datatypes of the numeric and boolean literals
+const (
+    xsdInteger = "http://www.w3.org/2001/XMLSchema#integer"
+    xsdDecimal = "http://www.w3.org/2001/XMLSchema#decimal"
+    xsdBoolean = "http://www.w3.org/2001/XMLSchema#boolean"
+)
+
+// The terms the grammar writes with a keyword
+var (
+    rdfType = Term{ Kind : IRI, Value : "http://www.w3.org/1999/02/22-rdf-syntax-ns#type" }
+    rdfFirst = Term{ Kind : IRI, Value : "http://www.w3.org/1999/02/22-rdf-syntax-ns#first" }
+    rdfRest = Term{ Kind : IRI, Value : "http://www.w3.org/1999/02/22-rdf-syntax-ns#rest" }
+    rdfNil = Term{ Kind : IRI, Value : "http://www.w3.org/1999/02/22-rdf-syntax-ns#nil" }
+    trueLiteral = Term{ Kind : Literal, Value : "true", Datatype : xsdBoolean }
+    falseLiteral = Term{ Kind : Literal, Value : "false", Datatype : xsdBoolean }
+)
+
+// String returns the term in SPARQL syntax, or an empty string if it is invalid
+func (t Term) String() string {
+    switch t.Kind {
+    case Invalid:
+        return ""
+    case IRI:
+        return "<" + t.Value + ">"
+    case Variable:
+        return "?" + t.Value
+    case BlankNode:
+        return "_:" + t.Value
+    }
+    s := `"` + literalEscaper.Replace(t.Value) + `"`
+    if t.Lang != "" {
+        return s + "@" + t.Lang
+    }
+    if t.Datatype != "" {
+        return s + "^^<" + t.Datatype + ">"
+    }
+    return s
+}
+
+var literalEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// TriplePattern is a triple pattern of a query, whose predicate is an IRI
+type TriplePattern struct {
+    Subject, Predicate, Object Term
+}
+
+// String returns the triple pattern in SPARQL syntax, e.g., ?s <p> ?o .
+func (tp TriplePattern) String() string {
+    return tp.Subject.String() + " " + tp.Predicate.String() + " " + tp.Object.String() + " ."
+}
+
+// variable returns the variable written as ?name or $name
+func variable(s string) Term {
+    return Term{ Kind : Variable, Value : s[1:] }
+}
+
+// numeric returns the integer or decimal literal
+func numeric(s string) Term {
+    if strings.IndexByte(s, '.') != -1 {
+        return Term{ Kind : Literal, Value : s, Datatype : xsdDecimal }
+    }
+    return Term{ Kind : Literal, Value : s, Datatype : xsdInteger }
+}
+
+// literal returns the literal written as s, i.e., a quoted string followed by
+// a language tag or by ^^ and the datatype, which is parsed by the caller
+func literal(s, datatype string) Term {
+    quote := s[:1]
+    if strings.HasPrefix(s, `"""`) || strings.HasPrefix(s, "'''") {
+        quote = s[:3]
+    }
+    // the end of the string, its escaped characters being skipped
+    end := len(quote)
+    for !strings.HasPrefix(s[end:], quote) {
+        if s[end] == '\\' {
+            end++
+        }
+        end++
+    }
+    t := Term{ Kind : Literal, Value : unescape(s[len(quote):end]) }
+    switch suffix := s[end + len(quote):]; {
+    case strings.HasPrefix(suffix, "@"):
+        t.Lang = strings.ToLower(suffix[1:])
+    case strings.HasPrefix(suffix, "^^"):
+        t.Datatype = datatype
+    }
+    return t
+}
+
+// unescape returns the string with its escaped characters, e.g., \n, replaced
+func unescape(s string) string {
+    if strings.IndexByte(s, '\\') == -1 {
+        return s
+    }
+    var sb strings.Builder
+    for i := 0; i < len(s); i++ {
+        if s[i] == '\\' && i + 1 < len(s) {
+            i++
+            switch s[i] {
+            case 't':
+                sb.WriteByte('\t')
+            case 'b':
+                sb.WriteByte('\b')
+            case 'n':
+                sb.WriteByte('\n')
+            case 'r':
+                sb.WriteByte('\r')
+            case 'f':
+                sb.WriteByte('\f')
+            default:
+                sb.WriteByte(s[i])
+            }
+            continue
+        }
+        sb.WriteByte(s[i])
+    }
+    return sb.String()
+}